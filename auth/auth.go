@@ -1,17 +1,25 @@
-// auth/auth_validation.go
 package auth
 
 import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ddessilvestri/ecommerce-go/models"
 )
 
+type tokenHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
 type tokenJSON struct {
 	Sub       string
 	Event_Id  string
@@ -22,13 +30,76 @@ type tokenJSON struct {
 	Exp       int
 	Iat       int
 	Client_id string
+	Aud       audience
 	Username  string
+	Email     string
+	Groups    []string `json:"cognito:groups"`
+}
+
+// audience accepts both the string and the array form of the "aud" claim
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// VerifierConfig lists the claims every accepted token must carry
+type VerifierConfig struct {
+	Keys     *KeySet
+	Issuer   string // expected "iss"
+	ClientID string // expected "client_id" (access tokens) or "aud" (id tokens)
+	TokenUse string // expected "token_use", e.g. "access"
+}
+
+// Verifier checks RS256 signatures against a JWKS and validates the standard claims
+type Verifier struct {
+	config VerifierConfig
+	now    func() time.Time
+}
+
+// NewVerifier fails when the issuer or client id is missing: both claims are always enforced
+func NewVerifier(config VerifierConfig) (*Verifier, error) {
+	if config.Issuer == "" {
+		return nil, fmt.Errorf("%w: issuer", ErrVerifierMisconfigured)
+	}
+	if config.ClientID == "" {
+		return nil, fmt.Errorf("%w: client id", ErrVerifierMisconfigured)
+	}
+	return &Verifier{config: config, now: time.Now}, nil
+}
+
+var (
+	verifierMu      sync.RWMutex
+	defaultVerifier *Verifier
+)
+
+// Configure sets the verifier used by ExtractAuthUser
+func Configure(v *Verifier) {
+	verifierMu.Lock()
+	defer verifierMu.Unlock()
+	defaultVerifier = v
+}
+
+func currentVerifier() *Verifier {
+	verifierMu.RLock()
+	defer verifierMu.RUnlock()
+	return defaultVerifier
 }
 
 func ExtractAuthUser(header map[string]string) (*models.AuthUser, error) {
 	rawAuth := header["authorization"]
 	if rawAuth == "" {
-		return nil, errors.New("missing authorization header")
+		return nil, ErrMissingToken
 	}
 
 	var token string
@@ -38,48 +109,129 @@ func ExtractAuthUser(header map[string]string) (*models.AuthUser, error) {
 		token = rawAuth
 	}
 
-	// Ahora validamos el token
-	isOk, uuid, err := tokenValidation(token)
-	if !isOk {
-		if err != nil {
-			return nil, err
-		}
-		return nil, errors.New("invalid token")
+	verifier := currentVerifier()
+	if verifier == nil {
+		return nil, ErrVerifierNotConfigured
+	}
+
+	tkj, err := verifier.Verify(token)
+	if err != nil {
+		fmt.Println("Token rejected:", err.Error())
+		return nil, err
 	}
 
-	// Creamos y devolvemos el AuthUser
 	return &models.AuthUser{
-		UUID: uuid,
+		UUID:      tkj.Username,
+		Sub:       tkj.Sub,
+		Username:  tkj.Username,
+		Email:     tkj.Email,
+		Groups:    tkj.Groups,
+		Scopes:    strings.Fields(tkj.Scope),
+		AuthTime:  unixTime(tkj.Auth_time),
+		IssuedAt:  unixTime(tkj.Iat),
+		ExpiresAt: unixTime(tkj.Exp),
 	}, nil
 }
 
-func tokenValidation(token string) (bool, string, error) {
+// unixTime converts a NumericDate claim, leaving missing claims as the zero time
+func unixTime(seconds int) time.Time {
+	if seconds == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(seconds), 0)
+}
+
+// Verify checks the signature of token and validates exp, iss, client_id/aud and token_use
+func (v *Verifier) Verify(token string) (*tokenJSON, error) {
 	parts := strings.Split(token, ".")
 	if len(parts) != 3 {
-		fmt.Println("Invalid Token. Length != 3")
-		return false, "Invalid Token", nil
+		return nil, fmt.Errorf("%w: expected 3 parts, got %d", ErrMalformedToken, len(parts))
 	}
 
-	rawPayload := parts[1]
-	payloadBytes, err := base64.RawURLEncoding.DecodeString(rawPayload)
+	var header tokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, header.Alg)
+	}
+
+	key, err := v.config.Keys.Key(header.Kid)
 	if err != nil {
-		fmt.Println("Decoding token error:", err.Error())
-		return false, err.Error(), err
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedToken, err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, ErrInvalidSignature
 	}
 
 	var tkj tokenJSON
-	if err := json.Unmarshal(payloadBytes, &tkj); err != nil {
-		fmt.Println("Cannot be decoded as JSON struct:", err.Error())
-		return false, err.Error(), err
+	if err := decodeSegment(parts[1], &tkj); err != nil {
+		return nil, err
 	}
 
-	now := time.Now()
+	if err := v.validateClaims(tkj); err != nil {
+		return nil, err
+	}
+
+	return &tkj, nil
+}
+
+func (v *Verifier) validateClaims(tkj tokenJSON) error {
 	expiration := time.Unix(int64(tkj.Exp), 0)
-	if expiration.Before(now) {
-		fmt.Println("Expiration date taken =", expiration.String())
-		fmt.Println("Expired Token")
-		return false, "Expired Token", nil
+	if tkj.Exp == 0 || expiration.Before(v.now()) {
+		return ErrTokenExpired
 	}
 
-	return true, string(tkj.Username), err
+	if tkj.Iss != v.config.Issuer {
+		return fmt.Errorf("%w: %q", ErrInvalidIssuer, tkj.Iss)
+	}
+
+	if tkj.Client_id != v.config.ClientID && !tkj.Aud.contains(v.config.ClientID) {
+		return ErrInvalidAudience
+	}
+
+	if v.config.TokenUse != "" && tkj.Token_use != v.config.TokenUse {
+		return fmt.Errorf("%w: %q", ErrInvalidTokenUse, tkj.Token_use)
+	}
+
+	return nil
+}
+
+func (a audience) contains(clientID string) bool {
+	for _, aud := range a {
+		if aud == clientID {
+			return true
+		}
+	}
+	return false
 }
+
+func decodeSegment(segment string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformedToken, err)
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformedToken, err)
+	}
+	return nil
+}
+
+var ErrMissingToken = errors.New("missing authorization header")
+var ErrVerifierNotConfigured = errors.New("token verification is not configured")
+var ErrVerifierMisconfigured = errors.New("token verification setting missing")
+var ErrMalformedToken = errors.New("malformed token")
+var ErrUnsupportedAlgorithm = errors.New("unsupported token algorithm")
+var ErrUnknownKey = errors.New("token signed with unknown key")
+var ErrKeySetUnavailable = errors.New("unable to load signing keys")
+var ErrInvalidSignature = errors.New("invalid token signature")
+var ErrTokenExpired = errors.New("expired token")
+var ErrInvalidIssuer = errors.New("invalid token issuer")
+var ErrInvalidAudience = errors.New("token issued for another client")
+var ErrInvalidTokenUse = errors.New("invalid token use")
@@ -0,0 +1,216 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	authContext "github.com/ddessilvestri/ecommerce-go/auth/context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testIssuer   = "https://cognito-idp.us-east-1.amazonaws.com/us-east-1_test"
+	testClientID = "client-123"
+)
+
+func newTestKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
+
+// writeJWKS writes the public part of keys (by kid) to a JWKS file
+func writeJWKS(t *testing.T, path string, keys map[string]*rsa.PrivateKey) {
+	var set jwks
+	for kid, key := range keys {
+		set.Keys = append(set.Keys, jwk{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			Alg: "RS256",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	raw, err := json.Marshal(set)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, raw, 0o600))
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, header map[string]string, claims map[string]interface{}) string {
+	rawHeader, err := json.Marshal(header)
+	require.NoError(t, err)
+	rawClaims, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	signingInput := base64.RawURLEncoding.EncodeToString(rawHeader) + "." + base64.RawURLEncoding.EncodeToString(rawClaims)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"sub":       "0f1e2d3c",
+		"username":  "user-123",
+		"iss":       testIssuer,
+		"client_id": testClientID,
+		"token_use": "access",
+		"exp":       time.Now().Add(time.Hour).Unix(),
+		"iat":       time.Now().Unix(),
+	}
+}
+
+func newTestVerifier(t *testing.T, keys map[string]*rsa.PrivateKey) (*Verifier, string) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, path, keys)
+
+	verifier, err := NewVerifier(VerifierConfig{
+		Keys:     NewKeySet(KeySetConfig{File: path, MinRefreshInterval: time.Nanosecond}),
+		Issuer:   testIssuer,
+		ClientID: testClientID,
+		TokenUse: "access",
+	})
+	require.NoError(t, err)
+	return verifier, path
+}
+
+// Test signature and claim validation with distinct errors
+func TestVerify(t *testing.T) {
+	key := newTestKey(t)
+	otherKey := newTestKey(t)
+	verifier, _ := newTestVerifier(t, map[string]*rsa.PrivateKey{"kid-1": key})
+	header := map[string]string{"alg": "RS256", "kid": "kid-1"}
+
+	with := func(field string, value interface{}) map[string]interface{} {
+		claims := validClaims()
+		claims[field] = value
+		return claims
+	}
+	without := func(field string) map[string]interface{} {
+		claims := validClaims()
+		delete(claims, field)
+		return claims
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{name: "Valid token", token: signToken(t, key, header, validClaims())},
+		{name: "Id token audience", token: signToken(t, key, header, with("aud", testClientID))},
+		{name: "Forged signature", token: signToken(t, otherKey, header, validClaims()), err: ErrInvalidSignature},
+		{name: "Unknown key id", token: signToken(t, key, map[string]string{"alg": "RS256", "kid": "kid-9"}, validClaims()), err: ErrUnknownKey},
+		{name: "Unsigned token", token: signToken(t, key, map[string]string{"alg": "none", "kid": "kid-1"}, validClaims()), err: ErrUnsupportedAlgorithm},
+		{name: "Expired token", token: signToken(t, key, header, with("exp", time.Now().Add(-time.Minute).Unix())), err: ErrTokenExpired},
+		{name: "Wrong issuer", token: signToken(t, key, header, with("iss", "https://evil.example.com")), err: ErrInvalidIssuer},
+		{name: "Wrong client", token: signToken(t, key, header, with("client_id", "other-client")), err: ErrInvalidAudience},
+		{name: "Missing issuer", token: signToken(t, key, header, without("iss")), err: ErrInvalidIssuer},
+		{name: "Missing client", token: signToken(t, key, header, without("client_id")), err: ErrInvalidAudience},
+		{name: "Wrong token use", token: signToken(t, key, header, with("token_use", "id")), err: ErrInvalidTokenUse},
+		{name: "Malformed token", token: "not-a-token", err: ErrMalformedToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := verifier.Verify(tt.token)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "user-123", claims.Username)
+		})
+	}
+}
+
+// Test a verifier cannot be built without an issuer or client id to enforce
+func TestNewVerifierRequiresIssuerAndClient(t *testing.T) {
+	keys := NewKeySet(KeySetConfig{File: filepath.Join(t.TempDir(), "jwks.json")})
+
+	_, err := NewVerifier(VerifierConfig{Keys: keys, ClientID: testClientID})
+	assert.ErrorIs(t, err, ErrVerifierMisconfigured)
+
+	_, err = NewVerifier(VerifierConfig{Keys: keys, Issuer: testIssuer})
+	assert.ErrorIs(t, err, ErrVerifierMisconfigured)
+}
+
+// Test a JWKS that never loaded is not fetched again before MinRefreshInterval
+func TestKeySetThrottlesColdRefresh(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	keys := NewKeySet(KeySetConfig{File: path, MinRefreshInterval: time.Hour})
+
+	_, err := keys.Key("kid-1")
+	assert.ErrorIs(t, err, ErrKeySetUnavailable)
+
+	// The file shows up, but the failed attempt is too recent to try again
+	writeJWKS(t, path, map[string]*rsa.PrivateKey{"kid-1": newTestKey(t)})
+	_, err = keys.Key("kid-1")
+	assert.ErrorIs(t, err, ErrKeySetUnavailable)
+
+	keys.lastAttempt = time.Time{}
+	_, err = keys.Key("kid-1")
+	assert.NoError(t, err)
+}
+
+// Test a token signed with a rotated-in key is accepted once the JWKS is reloaded
+func TestVerifyKeyRotation(t *testing.T) {
+	oldKey := newTestKey(t)
+	newKey := newTestKey(t)
+	verifier, path := newTestVerifier(t, map[string]*rsa.PrivateKey{"kid-1": oldKey})
+
+	_, err := verifier.Verify(signToken(t, oldKey, map[string]string{"alg": "RS256", "kid": "kid-1"}, validClaims()))
+	require.NoError(t, err)
+
+	writeJWKS(t, path, map[string]*rsa.PrivateKey{"kid-1": oldKey, "kid-2": newKey})
+
+	_, err = verifier.Verify(signToken(t, newKey, map[string]string{"alg": "RS256", "kid": "kid-2"}, validClaims()))
+	assert.NoError(t, err)
+}
+
+// Test ExtractAuthUser reads the bearer token and fills the user
+func TestExtractAuthUser(t *testing.T) {
+	key := newTestKey(t)
+	verifier, _ := newTestVerifier(t, map[string]*rsa.PrivateKey{"kid-1": key})
+	Configure(verifier)
+	defer Configure(nil)
+
+	claims := validClaims()
+	claims["cognito:groups"] = []string{"admins", "staff"}
+	claims["scope"] = "aws.cognito.signin.user.admin openid"
+	claims["auth_time"] = claims["iat"]
+	token := signToken(t, key, map[string]string{"alg": "RS256", "kid": "kid-1"}, claims)
+
+	user, err := ExtractAuthUser(map[string]string{"authorization": "Bearer " + token})
+	require.NoError(t, err)
+	assert.Equal(t, "user-123", user.UUID)
+	assert.Equal(t, "user-123", user.Username)
+	assert.Equal(t, "0f1e2d3c", user.Sub)
+	assert.Equal(t, []string{"admins", "staff"}, user.Groups)
+	assert.Equal(t, []string{"aws.cognito.signin.user.admin", "openid"}, user.Scopes)
+	assert.Equal(t, claims["exp"], user.ExpiresAt.Unix())
+	assert.Equal(t, claims["iat"], user.AuthTime.Unix())
+
+	ctx := authContext.WithUser(context.Background(), user)
+	assert.True(t, authContext.HasGroup(ctx, "admins"))
+	assert.False(t, authContext.HasGroup(ctx, "customers"))
+	assert.True(t, authContext.HasScope(ctx, "openid"))
+	assert.False(t, authContext.HasScope(ctx, "email"))
+
+	_, err = ExtractAuthUser(map[string]string{})
+	assert.ErrorIs(t, err, ErrMissingToken)
+}
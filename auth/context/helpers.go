@@ -2,7 +2,6 @@ package context
 
 import (
 	"context"
-	"errors"
 
 	"github.com/ddessilvestri/ecommerce-go/models"
 )
@@ -16,15 +15,33 @@ func UserFromContext(ctx context.Context) (*models.AuthUser, bool) {
 	return u, ok
 }
 
-func IsAdmin(ctx context.Context) bool {
-	_, ok := UserFromContext(ctx)
-	return ok && true
-}
-
 func UserUUIDFromContext(ctx context.Context) (string, error) {
 	u, ok := ctx.Value(AuthUserKey()).(*models.AuthUser)
-	if !ok {
-		return "", errors.New("cannot retrieve user UUID from context")
+	if !ok || u == nil {
+		return "", ErrUserNotInContext
 	}
 	return u.UUID, nil
 }
+
+var ErrUserNotInContext = models.NewUnauthorizedError("cannot retrieve user UUID from context")
+
+// HasGroup reports whether the user in ctx belongs to the given Cognito group
+func HasGroup(ctx context.Context, group string) bool {
+	u, ok := UserFromContext(ctx)
+	return ok && u != nil && contains(u.Groups, group)
+}
+
+// HasScope reports whether the token of the user in ctx was granted scope
+func HasScope(ctx context.Context, scope string) bool {
+	u, ok := UserFromContext(ctx)
+	return ok && u != nil && contains(u.Scopes, scope)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
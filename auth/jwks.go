@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// KeySetConfig says where the JSON Web Key Set is loaded from.
+// File takes precedence over URL so tokens can be verified offline.
+type KeySetConfig struct {
+	URL  string
+	File string
+
+	// How long fetched keys are trusted before being reloaded
+	CacheTTL time.Duration
+	// Minimum time between reloads triggered by an unknown key id
+	MinRefreshInterval time.Duration
+}
+
+// KeySet caches the RSA public keys of a JWKS by key id and reloads them on rotation
+type KeySet struct {
+	config KeySetConfig
+	client *http.Client
+
+	mu          sync.Mutex
+	keys        map[string]*rsa.PublicKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+	lastErr     error // why the last reload failed, returned while a cold set is throttled
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+func NewKeySet(config KeySetConfig) *KeySet {
+	if config.CacheTTL == 0 {
+		config.CacheTTL = time.Hour
+	}
+	if config.MinRefreshInterval == 0 {
+		config.MinRefreshInterval = time.Minute
+	}
+	return &KeySet{
+		config: config,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Key returns the public key for kid, reloading the set when the cache expired
+// or when kid is unknown (keys were rotated), at most once per MinRefreshInterval.
+func (k *KeySet) Key(kid string) (*rsa.PublicKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	expired := time.Since(k.fetchedAt) > k.config.CacheTTL
+	key, known := k.keys[kid]
+
+	if expired || !known {
+		if time.Since(k.lastAttempt) >= k.config.MinRefreshInterval {
+			if err := k.refresh(); err != nil {
+				k.lastErr = err
+				// Keep serving cached keys if a reload fails
+				if !known {
+					return nil, err
+				}
+				fmt.Println("JWKS refresh failed, using cached keys:", err.Error())
+			}
+			key, known = k.keys[kid]
+		} else if k.keys == nil {
+			// No keys were ever loaded and the last attempt is too recent to retry
+			return nil, k.lastErr
+		}
+	}
+
+	if !known {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// refresh must be called with k.mu held
+func (k *KeySet) refresh() error {
+	k.lastAttempt = time.Now()
+
+	raw, err := k.load()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrKeySetUnavailable, err)
+	}
+
+	var set jwks
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return fmt.Errorf("%w: %v", ErrKeySetUnavailable, err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, key := range set.Keys {
+		if key.Kty != "RSA" || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+		pub, err := key.publicKey()
+		if err != nil {
+			fmt.Println("Skipping invalid JWK", key.Kid, ":", err.Error())
+			continue
+		}
+		keys[key.Kid] = pub
+	}
+
+	k.keys = keys
+	k.fetchedAt = time.Now()
+	return nil
+}
+
+func (k *KeySet) load() ([]byte, error) {
+	if k.config.File != "" {
+		return os.ReadFile(k.config.File)
+	}
+	if k.config.URL == "" {
+		return nil, errors.New("no JWKS URL or file configured")
+	}
+
+	resp, err := k.client.Get(k.config.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d from %s", resp.StatusCode, k.config.URL)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (key jwk) publicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(key.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(key.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %w", err)
+	}
+
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() < 3 {
+		return nil, errors.New("invalid exponent")
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(exponent.Int64()),
+	}, nil
+}
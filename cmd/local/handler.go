@@ -0,0 +1,125 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/ddessilvestri/ecommerce-go/routers"
+	"github.com/ddessilvestri/ecommerce-go/routers/route"
+)
+
+// Handler adapts net/http requests to the API Gateway events consumed by routers.Router
+type Handler struct {
+	urlPrefix string
+	db        *sql.DB
+	routes    *route.Table
+}
+
+func NewHandler(urlPrefix string, db *sql.DB) *Handler {
+	return &Handler{urlPrefix: urlPrefix, db: db, routes: routers.NewRouteTable(db)}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	request, err := toGatewayRequest(r)
+	if err != nil {
+		http.Error(w, "Unable to read request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := routers.Router(request, h.urlPrefix, h.routes, h.db)
+	if err := writeGatewayResponse(w, response); err != nil {
+		fmt.Println("Error writing response:", err.Error())
+	}
+}
+
+// toGatewayRequest builds the payload API Gateway (HTTP API, v2) would send to the Lambda.
+// Path parameters are left empty: routers.Router extracts them from RawPath.
+func toGatewayRequest(r *http.Request) (events.APIGatewayV2HTTPRequest, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return events.APIGatewayV2HTTPRequest{}, err
+	}
+
+	// API Gateway lower-cases header names and joins repeated values with commas
+	headers := make(map[string]string, len(r.Header))
+	for key, values := range r.Header {
+		headers[strings.ToLower(key)] = strings.Join(values, ",")
+	}
+	if r.Host != "" {
+		headers["host"] = r.Host
+	}
+
+	query := make(map[string]string)
+	for key, values := range r.URL.Query() {
+		query[key] = strings.Join(values, ",")
+	}
+
+	var cookies []string
+	for _, c := range r.Cookies() {
+		cookies = append(cookies, c.String())
+	}
+
+	request := events.APIGatewayV2HTTPRequest{
+		Version:               "2.0",
+		RouteKey:              "$default",
+		RawPath:               r.URL.Path,
+		RawQueryString:        r.URL.RawQuery,
+		Cookies:               cookies,
+		Headers:               headers,
+		QueryStringParameters: query,
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			RequestID: fmt.Sprintf("local-%d", time.Now().UnixNano()),
+			Stage:     "$default",
+			Time:      time.Now().Format("02/Jan/2006:15:04:05 -0700"),
+			TimeEpoch: time.Now().UnixMilli(),
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				Protocol:  r.Proto,
+				SourceIP:  r.RemoteAddr,
+				UserAgent: r.UserAgent(),
+			},
+		},
+	}
+
+	if utf8.Valid(body) {
+		request.Body = string(body)
+	} else {
+		request.Body = base64.StdEncoding.EncodeToString(body)
+		request.IsBase64Encoded = true
+	}
+
+	return request, nil
+}
+
+func writeGatewayResponse(w http.ResponseWriter, response *events.APIGatewayProxyResponse) error {
+	for key, value := range response.Headers {
+		w.Header().Set(key, value)
+	}
+	for key, values := range response.MultiValueHeaders {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+
+	body := []byte(response.Body)
+	if response.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(response.Body)
+		if err != nil {
+			return err
+		}
+		body = decoded
+	}
+
+	w.WriteHeader(response.StatusCode)
+	_, err := w.Write(body)
+	return err
+}
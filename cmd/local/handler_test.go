@@ -0,0 +1,162 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test the request maps path, query, headers and body the way API Gateway does
+func TestToGatewayRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		headers map[string][]string
+		body    string
+		check   func(t *testing.T, request events.APIGatewayV2HTTPRequest)
+	}{
+		{
+			name:   "Path and method",
+			method: http.MethodDelete,
+			target: "/ecommerce/product/7",
+			check: func(t *testing.T, request events.APIGatewayV2HTTPRequest) {
+				assert.Equal(t, "/ecommerce/product/7", request.RawPath)
+				assert.Equal(t, "/ecommerce/product/7", request.RequestContext.HTTP.Path)
+				assert.Equal(t, http.MethodDelete, request.RequestContext.HTTP.Method)
+				assert.Empty(t, request.PathParameters)
+			},
+		},
+		{
+			name:   "Query joins repeated values",
+			method: http.MethodGet,
+			target: "/ecommerce/product?categId=2&tag=a&tag=b",
+			check: func(t *testing.T, request events.APIGatewayV2HTTPRequest) {
+				assert.Equal(t, "categId=2&tag=a&tag=b", request.RawQueryString)
+				assert.Equal(t, map[string]string{"categId": "2", "tag": "a,b"}, request.QueryStringParameters)
+			},
+		},
+		{
+			name:    "Headers are lower-cased and joined",
+			method:  http.MethodGet,
+			target:  "/ecommerce/order",
+			headers: map[string][]string{"Authorization": {"Bearer abc"}, "X-Trace": {"1", "2"}},
+			check: func(t *testing.T, request events.APIGatewayV2HTTPRequest) {
+				assert.Equal(t, "Bearer abc", request.Headers["authorization"])
+				assert.Equal(t, "1,2", request.Headers["x-trace"])
+				assert.Equal(t, "example.com", request.Headers["host"])
+			},
+		},
+		{
+			name:   "Text body",
+			method: http.MethodPost,
+			target: "/ecommerce/category",
+			body:   `{"categName":"Shoes"}`,
+			check: func(t *testing.T, request events.APIGatewayV2HTTPRequest) {
+				assert.Equal(t, `{"categName":"Shoes"}`, request.Body)
+				assert.False(t, request.IsBase64Encoded)
+			},
+		},
+		{
+			name:   "Binary body is base64 encoded",
+			method: http.MethodPost,
+			target: "/ecommerce/product",
+			body:   "\xff\xfe",
+			check: func(t *testing.T, request events.APIGatewayV2HTTPRequest) {
+				assert.Equal(t, "//4=", request.Body)
+				assert.True(t, request.IsBase64Encoded)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			for key, values := range tt.headers {
+				for _, value := range values {
+					r.Header.Add(key, value)
+				}
+			}
+
+			request, err := toGatewayRequest(r)
+			require.NoError(t, err)
+			assert.Equal(t, "2.0", request.Version)
+			tt.check(t, request)
+		})
+	}
+}
+
+// Test the response writes status, headers and body, decoding base64 bodies
+func TestWriteGatewayResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		response events.APIGatewayProxyResponse
+		status   int
+		headers  map[string][]string
+		body     string
+		wantErr  bool
+	}{
+		{
+			name: "Text body",
+			response: events.APIGatewayProxyResponse{
+				StatusCode: http.StatusCreated,
+				Headers:    map[string]string{"Content-Type": "application/json"},
+				Body:       `{"id":3}`,
+			},
+			status:  http.StatusCreated,
+			headers: map[string][]string{"Content-Type": {"application/json"}},
+			body:    `{"id":3}`,
+		},
+		{
+			name: "Multi-value headers",
+			response: events.APIGatewayProxyResponse{
+				StatusCode:        http.StatusOK,
+				MultiValueHeaders: map[string][]string{"Set-Cookie": {"a=1", "b=2"}},
+			},
+			status:  http.StatusOK,
+			headers: map[string][]string{"Set-Cookie": {"a=1", "b=2"}},
+		},
+		{
+			name: "Base64 body is decoded",
+			response: events.APIGatewayProxyResponse{
+				StatusCode:      http.StatusOK,
+				Body:            "aGVsbG8=",
+				IsBase64Encoded: true,
+			},
+			status: http.StatusOK,
+			body:   "hello",
+		},
+		{
+			name: "Invalid base64 body",
+			response: events.APIGatewayProxyResponse{
+				StatusCode:      http.StatusOK,
+				Body:            "not base64!",
+				IsBase64Encoded: true,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			err := writeGatewayResponse(w, &tt.response)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, w.Code)
+			for key, values := range tt.headers {
+				assert.Equal(t, values, w.Header().Values(key))
+			}
+			assert.Equal(t, tt.body, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/ddessilvestri/ecommerce-go/auth"
+	"github.com/ddessilvestri/ecommerce-go/db"
+	"github.com/ddessilvestri/ecommerce-go/internal/app"
+	"github.com/ddessilvestri/ecommerce-go/internal/config"
+	"github.com/ddessilvestri/ecommerce-go/internal/product"
+)
+
+// Local development server.
+// Serves routers.Router over plain net/http so the API can be exercised without
+// API Gateway, AWS config or Secrets Manager.
+//
+//	DB_DSN="user:pass@tcp(localhost:3306)/gambit" go run ./cmd/local -addr :8080
+func main() {
+	addr := flag.String("addr", envOrDefault("LOCAL_ADDR", ":8080"), "address to listen on")
+	dsn := flag.String("dsn", os.Getenv("DB_DSN"), "MySQL DSN, e.g. user:pass@tcp(localhost:3306)/gambit")
+	urlPrefix := flag.String("prefix", os.Getenv("UrlPrefix"), "URL prefix stripped before routing")
+	flag.Parse()
+
+	if *dsn == "" {
+		fmt.Fprintln(os.Stderr, "missing database DSN: use -dsn or DB_DSN")
+		os.Exit(2)
+	}
+
+	conf, err := config.LoadConfig()
+	if err != nil {
+		log.Fatal("Config load failed: " + err.Error())
+	}
+	verifier, err := app.NewVerifier(conf)
+	if err != nil {
+		log.Fatal("Token verifier setup failed: " + err.Error())
+	}
+	auth.Configure(verifier)
+	if err := product.ConfigureSearch(conf.SearchBackend, conf.SearchIndexTTL); err != nil {
+		log.Fatal("Search setup failed: " + err.Error())
+	}
+	product.ConfigureSuggestions(conf.SuggestCacheTTL)
+
+	sqlDB, err := db.DbConnectDSN(*dsn)
+	if err != nil {
+		log.Fatal("Database connection error: " + err.Error())
+	}
+	defer sqlDB.Close()
+
+	server := &http.Server{
+		Addr:    *addr,
+		Handler: NewHandler(*urlPrefix, sqlDB),
+	}
+
+	fmt.Println("Local server listening on", *addr)
+	log.Fatal(server.ListenAndServe())
+}
+
+func envOrDefault(key, fallback string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return fallback
+}
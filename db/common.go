@@ -2,16 +2,24 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/ddessilvestri/ecommerce-go/models"
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 )
 
 // Builds and returns a new DB connection based on the given secret
 func DbConnectAndReturn(secret models.SecretRDSJson, dbName string) (*sql.DB, error) {
-	db, err := sql.Open("mysql", ConnStr(secret, dbName))
+	return DbConnectDSN(ConnStr(secret, dbName))
+}
+
+// Builds and returns a new DB connection from a raw MySQL DSN
+// (e.g. "user:pass@tcp(localhost:3306)/gambit"), used when running outside Lambda
+func DbConnectDSN(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("mysql", dsn)
 	if err != nil {
 		fmt.Println(err.Error())
 		return nil, err
@@ -20,6 +28,7 @@ func DbConnectAndReturn(secret models.SecretRDSJson, dbName string) (*sql.DB, er
 	err = db.Ping()
 	if err != nil {
 		fmt.Println(err.Error())
+		db.Close()
 		return nil, err
 	}
 
@@ -27,6 +36,26 @@ func DbConnectAndReturn(secret models.SecretRDSJson, dbName string) (*sql.DB, er
 	return db, nil
 }
 
+// Applies pool limits so a single connection pool can be reused across invocations
+func ConfigurePool(db *sql.DB, maxOpen, maxIdle int, maxLifetime, maxIdleTime time.Duration) {
+	db.SetMaxOpenConns(maxOpen)
+	db.SetMaxIdleConns(maxIdle)
+	db.SetConnMaxLifetime(maxLifetime)
+	db.SetConnMaxIdleTime(maxIdleTime)
+}
+
+// Reports whether err means the credentials were rejected (e.g. after a secret rotation)
+func IsAuthError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1045
+}
+
+// Reports whether err means InnoDB rolled the transaction back to break a deadlock; retrying may succeed
+func IsDeadlock(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1213
+}
+
 // Builds a MySQL connection string
 func ConnStr(json models.SecretRDSJson, dbName string) string {
 	dsn := fmt.Sprintf("%s:%s@tcp(%s)/%s?allowCleartextPasswords=true",
@@ -35,40 +64,49 @@ func ConnStr(json models.SecretRDSJson, dbName string) string {
 		json.Host,
 		dbName,
 	)
-	fmt.Println(dsn)
+	// Never log the DSN itself: it carries the password
+	fmt.Printf("Connecting to %s/%s as %s\n", json.Host, dbName, json.Username)
 	return dsn
 }
 
-// Verifies if a user is admin
-func UserIsAdmin(db *sql.DB, userUUID string) (bool, string) {
-	fmt.Println("Checking if user is admin:", userUUID)
-
+// Returns the role of a user based on User_Status
+func UserRole(db *sql.DB, userUUID string) (models.Role, error) {
 	query, args, err := squirrel.
-		Select("1").
+		Select("User_Status").
 		From("users").
-		Where(squirrel.Eq{
-			"User_UUID":   userUUID,
-			"User_Status": 0,
-		}).ToSql()
+		Where(squirrel.Eq{"User_UUID": userUUID}).
+		ToSql()
 
 	if err != nil {
-		return false, err.Error()
+		return "", err
+	}
+
+	var status int
+	err = db.QueryRow(query, args...).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", ErrUserNotFound
 	}
+	if err != nil {
+		return "", err
+	}
+
+	return models.RoleFromStatus(status), nil
+}
+
+// Verifies if a user is admin
+func UserIsAdmin(db *sql.DB, userUUID string) (bool, string) {
+	fmt.Println("Checking if user is admin:", userUUID)
 
-	rows, err := db.Query(query, args...)
+	role, err := UserRole(db, userUUID)
 	if err != nil {
 		return false, err.Error()
 	}
-	defer rows.Close()
-
-	var result int
-	if rows.Next() {
-		if err := rows.Scan(&result); err != nil {
-			return false, err.Error()
-		}
-		fmt.Println("User is admin")
-		return true, ""
+	if role != models.RoleAdmin {
+		return false, "User is not Admin"
 	}
 
-	return false, "User is not Admin"
+	fmt.Println("User is admin")
+	return true, ""
 }
+
+var ErrUserNotFound = errors.New("user not found")
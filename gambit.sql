@@ -41,7 +41,44 @@ CREATE TABLE IF NOT EXISTS `category` (
   `Categ_Id` int unsigned NOT NULL AUTO_INCREMENT,
   `Categ_Name` varchar(100) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci DEFAULT NULL,
   `Categ_Path` varchar(150) DEFAULT NULL,
-  PRIMARY KEY (`Categ_Id`)
+  `Categ_ParentId` int unsigned DEFAULT NULL COMMENT 'Parent category, NULL at the top level',
+  `Categ_Position` int NOT NULL DEFAULT '0' COMMENT 'Order among siblings, ties sorted by name',
+  `Categ_Depth` tinyint unsigned NOT NULL DEFAULT '0' COMMENT 'Distance from the top level',
+  PRIMARY KEY (`Categ_Id`),
+  KEY `Categ_ParentId` (`Categ_ParentId`,`Categ_Position`),
+  CONSTRAINT `FK_Category_Parent` FOREIGN KEY (`Categ_ParentId`) REFERENCES `category` (`Categ_Id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- La exportación de datos fue deseleccionada.
+
+-- Volcando estructura para tabla gambit.order_amendments
+CREATE TABLE IF NOT EXISTS `order_amendments` (
+  `OA_Id` int unsigned NOT NULL AUTO_INCREMENT,
+  `OA_OrderId` int unsigned NOT NULL,
+  `OA_Lines` json NOT NULL COMMENT 'Changed lines: prodId with the quantity before (from) and after (to)',
+  `OA_AddId` int unsigned DEFAULT NULL COMMENT 'New address, NULL when unchanged',
+  `OA_TotalBefore` decimal(20,2) NOT NULL,
+  `OA_TotalAfter` decimal(20,2) NOT NULL,
+  `OA_Actor` char(36) DEFAULT NULL COMMENT 'UUID of the user who amended the order',
+  `OA_Date` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`OA_Id`),
+  KEY `OA_OrderId` (`OA_OrderId`,`OA_Date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- La exportación de datos fue deseleccionada.
+
+-- Volcando estructura para tabla gambit.order_status_history
+CREATE TABLE IF NOT EXISTS `order_status_history` (
+  `OSH_Id` int unsigned NOT NULL AUTO_INCREMENT,
+  `OSH_OrderId` int unsigned NOT NULL,
+  `OSH_From` varchar(20) DEFAULT NULL COMMENT 'NULL when the order was placed',
+  `OSH_To` varchar(20) NOT NULL,
+  `OSH_Actor` char(36) DEFAULT NULL COMMENT 'UUID of the user who made the change',
+  `OSH_ActorRole` varchar(20) NOT NULL DEFAULT 'system' COMMENT 'customer, admin or system',
+  `OSH_Note` varchar(255) DEFAULT NULL,
+  `OSH_Date` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`OSH_Id`),
+  KEY `OSH_OrderId` (`OSH_OrderId`,`OSH_Date`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 
 -- La exportación de datos fue deseleccionada.
@@ -53,10 +90,13 @@ CREATE TABLE IF NOT EXISTS `orders` (
   `Order_AddId` int unsigned DEFAULT NULL,
   `Order_Date` datetime DEFAULT CURRENT_TIMESTAMP,
   `Order_Total` decimal(20,2) DEFAULT '0.00',
+  `Order_Status` enum('pending','paid','processing','shipped','delivered','cancelled','refunded') NOT NULL DEFAULT 'pending' COMMENT 'Lifecycle, see order.Service',
+  `Order_StockReserved` tinyint(1) NOT NULL DEFAULT '1' COMMENT 'Quantities were taken from Prod_Stock when placed',
   PRIMARY KEY (`Order_Id`) USING BTREE,
   KEY `Order_Date` (`Order_Date`),
   KEY `Order_UserId` (`Order_UserUUID`) USING BTREE,
-  KEY `Order_AddId` (`Order_AddId`)
+  KEY `Order_AddId` (`Order_AddId`),
+  KEY `Order_Status` (`Order_Status`)
 ) ENGINE=InnoDB AUTO_INCREMENT=3 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 
 -- La exportación de datos fue deseleccionada.
@@ -86,10 +126,24 @@ CREATE TABLE IF NOT EXISTS `products` (
   `Prod_Path` varchar(100) DEFAULT NULL,
   `Prod_CategoryId` mediumint DEFAULT NULL,
   `Prod_Stock` int DEFAULT '0',
+  `Prod_Status` enum('draft','active','archived') NOT NULL DEFAULT 'active' COMMENT 'Lifecycle: draft, active or archived',
   PRIMARY KEY (`Prod_Id`),
   KEY `Prod_CreatedAt` (`Prod_CreatedAt`),
   KEY `Prod_Updated` (`Prod_Updated`),
-  KEY `Prod_CategoryId` (`Prod_CategoryId`)
+  KEY `Prod_CategoryId` (`Prod_CategoryId`),
+  KEY `Prod_Status` (`Prod_Status`),
+  FULLTEXT KEY `Prod_FullText` (`Prod_Title`,`Prod_Description`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- La exportación de datos fue deseleccionada.
+
+-- Volcando estructura para tabla gambit.search_queries
+CREATE TABLE IF NOT EXISTS `search_queries` (
+  `SQ_Query` varchar(100) NOT NULL COMMENT 'Normalized search text: lowercase, single spaces',
+  `SQ_Hits` int unsigned NOT NULL DEFAULT '1' COMMENT 'Times the search was run and found products',
+  `SQ_LastSearched` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`SQ_Query`),
+  KEY `SQ_Hits` (`SQ_Hits`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
 
 -- La exportación de datos fue deseleccionada.
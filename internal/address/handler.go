@@ -30,16 +30,16 @@ func (h *Handler) Post(requestWithContext models.RequestWithContext) *events.API
 
 	err := json.Unmarshal([]byte(body), &a)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return tools.ErrorResponse(requestWithContext, models.NewValidationError("invalid JSON body").Wrap(err))
 	}
 	userUUID, err := authContext.UserUUIDFromContext(requestWithContext.Context())
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "User Not found In Context: "+err.Error())
+		return tools.ErrorResponse(requestWithContext, err)
 	}
 
 	id, err := h.service.Create(a, userUUID)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "Error : "+err.Error())
+		return tools.ErrorResponse(requestWithContext, err)
 	}
 
 	return tools.CreateAPIResponse(http.StatusOK, fmt.Sprintf(`{"AddressID": %d}`, id))
@@ -49,7 +49,7 @@ func (h *Handler) Put(requestWithContext models.RequestWithContext) *events.APIG
 	id := requestWithContext.RequestPathParameters()["id"]
 	idn, err := strconv.Atoi(id)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "Invalid AddressId: "+err.Error())
+		return tools.ErrorResponse(requestWithContext, models.NewFieldError("id", "invalid address Id").Wrap(err))
 	}
 
 	var a models.Address
@@ -57,14 +57,39 @@ func (h *Handler) Put(requestWithContext models.RequestWithContext) *events.APIG
 
 	err = json.Unmarshal([]byte(body), &a)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return tools.ErrorResponse(requestWithContext, models.NewValidationError("invalid JSON body").Wrap(err))
 	}
 
 	a.Id = idn
 
 	err = h.service.Update(a)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "Error : "+err.Error())
+		return tools.ErrorResponse(requestWithContext, err)
+	}
+
+	return tools.CreateAPIResponse(http.StatusOK, fmt.Sprintf(`{"Updated AddressId": %d}`, idn))
+}
+
+// Patch handles the HTTP PATCH request: a JSON Merge Patch of the address
+func (h *Handler) Patch(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	id := requestWithContext.RequestPathParameters()["id"]
+	idn, err := strconv.Atoi(id)
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, models.NewFieldError("id", "invalid address Id").Wrap(err))
+	}
+
+	var a models.AddressPatch
+	if err := tools.ParseMergePatch(requestWithContext.RequestBody(), &a); err != nil {
+		return tools.ErrorResponse(requestWithContext, err)
+	}
+	userUUID, err := authContext.UserUUIDFromContext(requestWithContext.Context())
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, err)
+	}
+
+	err = h.service.Patch(idn, userUUID, a)
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, err)
 	}
 
 	return tools.CreateAPIResponse(http.StatusOK, fmt.Sprintf(`{"Updated AddressId": %d}`, idn))
@@ -76,12 +101,12 @@ func (h *Handler) Delete(requestWithContext models.RequestWithContext) *events.A
 	id := requestWithContext.RequestPathParameters()["id"]
 	idn, err := strconv.Atoi(id)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "Invalid AddressId: "+err.Error())
+		return tools.ErrorResponse(requestWithContext, models.NewFieldError("id", "invalid address Id").Wrap(err))
 	}
 
 	err = h.service.Delete(idn)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "Error : "+err.Error())
+		return tools.ErrorResponse(requestWithContext, err)
 	}
 	return tools.CreateAPIResponse(http.StatusOK, fmt.Sprintf(`{"Deleted AddressId": %d}`, idn))
 
@@ -94,15 +119,15 @@ func (h *Handler) Get(requestWithContext models.RequestWithContext) *events.APIG
 	if idStr := query["id"]; idStr != "" {
 		id, err := strconv.Atoi(idStr)
 		if err != nil || id <= 0 {
-			return tools.CreateAPIResponse(http.StatusBadRequest, "Invalid 'id' parameter")
+			return tools.ErrorResponse(requestWithContext, models.NewFieldError("id", "invalid 'id' parameter"))
 		}
 		address, err := h.service.GetById(id)
 		if err != nil {
-			return tools.CreateAPIResponse(http.StatusNotFound, "Address not found: "+err.Error())
+			return tools.ErrorResponse(requestWithContext, err)
 		}
 		body, err := json.Marshal(address)
 		if err != nil {
-			return tools.CreateAPIResponse(http.StatusInternalServerError, "Error converting to JSON: "+err.Error())
+			return tools.ErrorResponse(requestWithContext, err)
 		}
 		return tools.CreateAPIResponse(http.StatusOK, string(body))
 	}
@@ -110,16 +135,17 @@ func (h *Handler) Get(requestWithContext models.RequestWithContext) *events.APIG
 	// === 2. Default: Get all addresses for user ===
 	userUUID, err := authContext.UserUUIDFromContext(requestWithContext.Context())
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "User Not found In Context: "+err.Error())
+		return tools.ErrorResponse(requestWithContext, err)
 	}
 
-	addresses, err := h.service.GetAllByUserUUID(userUUID)
+	q, err := QuerySpec.Parse(query)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusInternalServerError, err.Error())
+		return tools.ErrorResponse(requestWithContext, err)
 	}
-	body, err := json.Marshal(addresses)
+
+	addresses, err := h.service.GetAllByUserUUID(userUUID, q)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusInternalServerError, "Error converting to JSON: "+err.Error())
+		return tools.ErrorResponse(requestWithContext, err)
 	}
-	return tools.CreateAPIResponse(http.StatusOK, string(body))
+	return tools.ListResponse(requestWithContext, addresses, q)
 }
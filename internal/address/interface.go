@@ -1,12 +1,18 @@
 package address
 
-import "github.com/ddessilvestri/ecommerce-go/models"
+import (
+	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/ddessilvestri/ecommerce-go/tools"
+)
 
 type Storage interface {
 	Insert(a models.Address, userUUID string) (int64, error)
 	Update(a models.Address) error
+	Patch(id int, userUUID string, a models.AddressPatch) error
 	Delete(id int) error
 	GetById(id int) (models.Address, error)
-	GetAllByUserUUID(userUUID string) ([]models.Address, error)
+	GetAllByUserUUID(userUUID string, q tools.Query) ([]models.Address, error)
+	CountByUserUUID(userUUID string, q tools.Query) (int, error)
 	Exists(id int) bool
+	ExistsForUser(id int, userUUID string) bool
 }
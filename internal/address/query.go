@@ -0,0 +1,20 @@
+package address
+
+import "github.com/ddessilvestri/ecommerce-go/tools"
+
+// QuerySpec declares the address fields the address listing can sort, filter and select on
+var QuerySpec = tools.QuerySpec{
+	Fields: map[string]tools.Field{
+		"id":          {Column: "Add_Id", JSON: "id", Type: tools.IntField, Sortable: true},
+		"title":       {Column: "Add_Title", JSON: "title", Sortable: true, Filterable: true},
+		"name":        {Column: "Add_Name", JSON: "name", Sortable: true},
+		"address":     {Column: "Add_Address", JSON: "address"},
+		"city":        {Column: "Add_City", JSON: "city", Sortable: true, Filterable: true},
+		"state":       {Column: "Add_State", JSON: "state", Filterable: true},
+		"postal_code": {Column: "Add_PostalCode", JSON: "postalCode", Filterable: true},
+		"phone":       {Column: "Add_Phone", JSON: "phone"},
+	},
+	IdColumn:     "Add_Id",
+	DefaultSort:  "id",
+	DefaultOrder: "ASC",
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/Masterminds/squirrel"
 	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/ddessilvestri/ecommerce-go/tools"
 )
 
 // This struct acts like a "class" in Go.
@@ -86,6 +87,13 @@ func (r *repositorySQL) Exists(id int) bool {
 
 }
 
+// ExistsForUser reports whether the address exists and belongs to userUUID
+func (r *repositorySQL) ExistsForUser(id int, userUUID string) bool {
+	var exists int
+	err := r.db.QueryRow("SELECT 1 FROM addresses WHERE Add_Id = ? AND Add_UserID = ? LIMIT 1", id, userUUID).Scan(&exists)
+	return err == nil
+}
+
 func (r *repositorySQL) Update(a models.Address) error {
 	columns := []string{
 		"Add_Name",
@@ -133,6 +141,46 @@ func (r *repositorySQL) Update(a models.Address) error {
 	return nil
 }
 
+// Patch only updates the columns present in the patch, on an address of userUUID; a null state is stored as NULL
+func (r *repositorySQL) Patch(id int, userUUID string, a models.AddressPatch) error {
+	columns := map[string]interface{}{}
+	if a.Name.Set {
+		columns["Add_Name"] = a.Name.SQLValue()
+	}
+	if a.Title.Set {
+		columns["Add_Title"] = a.Title.SQLValue()
+	}
+	if a.Address.Set {
+		columns["Add_Address"] = a.Address.SQLValue()
+	}
+	if a.City.Set {
+		columns["Add_City"] = a.City.SQLValue()
+	}
+	if a.State.Set {
+		columns["Add_State"] = a.State.SQLValue()
+	}
+	if a.PostalCode.Set {
+		columns["Add_PostalCode"] = a.PostalCode.SQLValue()
+	}
+	if a.Phone.Set {
+		columns["Add_Phone"] = a.Phone.SQLValue()
+	}
+
+	query, args, err := squirrel.
+		Update("addresses").
+		PlaceholderFormat(squirrel.Question).
+		SetMap(columns).
+		Where(squirrel.Eq{"Add_Id": id, "Add_UserID": userUUID}).
+		ToSql()
+
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(query, args...)
+	return err
+}
+
 func (r *repositorySQL) Delete(id int) error {
 	query, args, err := squirrel.
 		Delete("addresses").
@@ -151,12 +199,12 @@ func (r *repositorySQL) Delete(id int) error {
 	return nil
 }
 
-func (r *repositorySQL) GetAllByUserUUID(userUUID string) ([]models.Address, error) {
-	queryBuilder := squirrel.
+func (r *repositorySQL) GetAllByUserUUID(userUUID string, q tools.Query) ([]models.Address, error) {
+	queryBuilder := q.ApplyTo(squirrel.
 		Select("*").
 		From("addresses").
 		Where(squirrel.Eq{"Add_UserID": userUUID}).
-		PlaceholderFormat(squirrel.Question)
+		PlaceholderFormat(squirrel.Question))
 
 	query, args, err := queryBuilder.ToSql()
 	if err != nil {
@@ -173,12 +221,13 @@ func (r *repositorySQL) GetAllByUserUUID(userUUID string) ([]models.Address, err
 	for rows.Next() {
 		var a models.Address
 		var userID string
+		var state sql.NullString
 		err = rows.Scan(
 			&a.Id,
 			&userID, // Add_UserID - we don't need this in the model
 			&a.Address,
 			&a.City,
-			&a.State,
+			&state,
 			&a.PostalCode,
 			&a.Phone,
 			&a.Title,
@@ -187,12 +236,30 @@ func (r *repositorySQL) GetAllByUserUUID(userUUID string) ([]models.Address, err
 		if err != nil {
 			return nil, err
 		}
+		a.State = state.String
 		addresses = append(addresses, a)
 	}
 
 	return addresses, nil
 }
 
+func (r *repositorySQL) CountByUserUUID(userUUID string, q tools.Query) (int, error) {
+	query, args, err := q.ApplyFiltersTo(squirrel.
+		Select("COUNT(*)").
+		From("addresses").
+		Where(squirrel.Eq{"Add_UserID": userUUID}).
+		PlaceholderFormat(squirrel.Question)).
+		ToSql()
+
+	if err != nil {
+		return 0, err
+	}
+
+	var total int
+	err = r.db.QueryRow(query, args...).Scan(&total)
+	return total, err
+}
+
 func (r *repositorySQL) GetById(id int) (models.Address, error) {
 	query, args, err := squirrel.
 		Select("*").
@@ -208,12 +275,13 @@ func (r *repositorySQL) GetById(id int) (models.Address, error) {
 
 	var a models.Address
 	var userID string
+	var state sql.NullString
 	err = r.db.QueryRow(query, args...).Scan(
 		&a.Id,
 		&userID, // Add_UserID - we don't need this in the model
 		&a.Address,
 		&a.City,
-		&a.State,
+		&state,
 		&a.PostalCode,
 		&a.Phone,
 		&a.Title,
@@ -223,6 +291,7 @@ func (r *repositorySQL) GetById(id int) (models.Address, error) {
 	if err != nil {
 		return models.Address{}, err
 	}
+	a.State = state.String
 
 	return a, nil
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/ddessilvestri/ecommerce-go/routers/route"
 )
 
 type Router struct {
@@ -18,6 +19,16 @@ func NewRouter(db *sql.DB) *Router {
 	return &Router{handler: handler}
 }
 
+// RegisterRoutes declares the address endpoints on the route table
+func RegisterRoutes(table *route.Table, db *sql.DB) {
+	r := NewRouter(db)
+	table.Handle(route.GET, "/address", r.Get)
+	table.Handle(route.POST, "/address", r.Post)
+	table.Handle(route.PUT, "/address/{id}", r.Put)
+	table.Handle(route.PATCH, "/address/{id}", r.Patch)
+	table.Handle(route.DELETE, "/address/{id}", r.Delete)
+}
+
 func (r *Router) Post(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
 	return r.handler.Post(requestWithContext)
 }
@@ -30,6 +41,10 @@ func (r *Router) Put(requestWithContext models.RequestWithContext) *events.APIGa
 	return r.handler.Put(requestWithContext)
 }
 
+func (r *Router) Patch(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	return r.handler.Patch(requestWithContext)
+}
+
 func (r *Router) Delete(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
 	return r.handler.Delete(requestWithContext)
 }
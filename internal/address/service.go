@@ -1,9 +1,8 @@
 package address
 
 import (
-	"errors"
-
 	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/ddessilvestri/ecommerce-go/tools"
 )
 
 // Service provides methods for business logic related to address.
@@ -71,6 +70,38 @@ func (s *Service) Update(a models.Address) error {
 
 }
 
+// Patch applies a JSON Merge Patch to an address of userUUID; only the state can be cleared, the other fields are required.
+// Addresses of other users are reported as not found.
+func (s *Service) Patch(id int, userUUID string, a models.AddressPatch) error {
+	if userUUID == "" {
+		return ErrMissingUUID
+	}
+	if id < 1 {
+		return ErrInvalidId
+	}
+	required := []struct {
+		field models.Optional[string]
+		err   error
+	}{
+		{a.Address, ErrMissingAddress},
+		{a.Name, ErrMissingName},
+		{a.Title, ErrMissingTitle},
+		{a.City, ErrMissingCity},
+		{a.Phone, ErrMissingPhone},
+		{a.PostalCode, ErrMissingPostalCode},
+	}
+	for _, r := range required {
+		if r.field.Set && (r.field.Null || r.field.Value == "") {
+			return r.err
+		}
+	}
+	if !s.repo.ExistsForUser(id, userUUID) {
+		return ErrIdNotFound
+	}
+
+	return s.repo.Patch(id, userUUID, a)
+}
+
 func (s *Service) Delete(id int) error {
 	if id < 1 {
 		return ErrInvalidId
@@ -89,16 +120,24 @@ func (s *Service) GetById(id int) (models.Address, error) {
 	return s.repo.GetById(id)
 }
 
-func (s *Service) GetAllByUserUUID(userUUID string) ([]models.Address, error) {
-	return s.repo.GetAllByUserUUID(userUUID)
+func (s *Service) GetAllByUserUUID(userUUID string, q tools.Query) (models.Page[models.Address], error) {
+	addresses, err := s.repo.GetAllByUserUUID(userUUID, q)
+	if err != nil {
+		return models.Page[models.Address]{}, err
+	}
+	total, err := s.repo.CountByUserUUID(userUUID, q)
+	if err != nil {
+		return models.Page[models.Address]{}, err
+	}
+	return tools.NewListPage(addresses, q, total, nil), nil
 }
 
-var ErrMissingAddress = errors.New("missing address ")
-var ErrMissingName = errors.New("missing name ")
-var ErrMissingTitle = errors.New("missing title ")
-var ErrMissingCity = errors.New("missing city ")
-var ErrMissingPostalCode = errors.New("missing postal code ")
-var ErrMissingPhone = errors.New("missing phone ")
-var ErrMissingUUID = errors.New("missing UUID ")
-var ErrIdNotFound = errors.New("missing Id ")
-var ErrInvalidId = errors.New("invalid Id ")
+var ErrMissingAddress = models.NewFieldError("address", "missing address")
+var ErrMissingName = models.NewFieldError("name", "missing name")
+var ErrMissingTitle = models.NewFieldError("title", "missing title")
+var ErrMissingCity = models.NewFieldError("city", "missing city")
+var ErrMissingPostalCode = models.NewFieldError("postalCode", "missing postal code")
+var ErrMissingPhone = models.NewFieldError("phone", "missing phone")
+var ErrMissingUUID = models.NewUnauthorizedError("missing UUID")
+var ErrIdNotFound = models.NewNotFoundError("address not found")
+var ErrInvalidId = models.NewFieldError("id", "invalid Id")
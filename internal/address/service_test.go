@@ -0,0 +1,40 @@
+package address
+
+import (
+	"testing"
+
+	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ownerStore keeps the owner of each address in memory; other Storage methods are not used
+type ownerStore struct {
+	Storage
+	owners  map[int]string
+	patched []int
+}
+
+func (s *ownerStore) ExistsForUser(id int, userUUID string) bool {
+	owner, ok := s.owners[id]
+	return ok && owner == userUUID
+}
+
+func (s *ownerStore) Patch(id int, userUUID string, a models.AddressPatch) error {
+	s.patched = append(s.patched, id)
+	return nil
+}
+
+// Test an address can only be patched by its owner; anyone else gets not found
+func TestPatchChecksOwner(t *testing.T) {
+	store := &ownerStore{owners: map[int]string{7: "user-1"}}
+	service := NewService(store)
+	patch := models.AddressPatch{City: models.Optional[string]{Set: true, Value: "Lima"}}
+
+	err := service.Patch(7, "user-2", patch)
+	assert.ErrorIs(t, err, ErrIdNotFound)
+	assert.Empty(t, store.patched)
+
+	require.NoError(t, service.Patch(7, "user-1", patch))
+	assert.Equal(t, []int{7}, store.patched)
+}
@@ -1,7 +1,6 @@
 package adminusers
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
 
@@ -23,20 +22,16 @@ func (h *Handler) Get(requestWithContext models.RequestWithContext) *events.APIG
 	// Verify if the user is admin
 
 	query := requestWithContext.RequestQueryStringParameters()
-	page, limit, sortBy, order, err := tools.ParsePaginationAndSorting(query)
+	q, err := QuerySpec.Parse(query)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, err.Error())
+		return tools.ErrorResponse(requestWithContext, err)
 	}
 
-	users, err := h.service.GetAll(page, limit, sortBy, order)
+	users, err := h.service.GetAll(q)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusInternalServerError, err.Error())
+		return tools.ErrorResponse(requestWithContext, err)
 	}
-	body, err := json.Marshal(users)
-	if err != nil {
-		return tools.CreateAPIResponse(http.StatusInternalServerError, "error converting to JSON: "+err.Error())
-	}
-	return tools.CreateAPIResponse(http.StatusOK, string(body))
+	return tools.ListResponse(requestWithContext, users, q)
 }
 
 func (h *Handler) Delete(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
@@ -44,13 +39,13 @@ func (h *Handler) Delete(requestWithContext models.RequestWithContext) *events.A
 
 	uuid := requestWithContext.RequestPathParameters()["id"]
 	if uuid == "" {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "invalid UUID")
+		return tools.ErrorResponse(requestWithContext, models.NewFieldError("id", "invalid UUID"))
 	}
 
 	err := h.service.Delete(uuid)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "error: "+err.Error())
+		return tools.ErrorResponse(requestWithContext, err)
 	}
 
-	return tools.CreateAPIResponse(http.StatusOK, fmt.Sprintf("product deleted: %s", uuid))
+	return tools.CreateAPIResponse(http.StatusOK, fmt.Sprintf(`{"DeletedUserUUID": %q}`, uuid))
 }
@@ -1,8 +1,12 @@
 package adminusers
 
-import "github.com/ddessilvestri/ecommerce-go/models"
+import (
+	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/ddessilvestri/ecommerce-go/tools"
+)
 
 type Storage interface {
 	Delete(uuid string) error
-	GetAll(page, limit int, sortBy, order string) ([]models.User, error)
+	GetAll(q tools.Query) ([]models.User, error)
+	Count(q tools.Query) (int, error)
 }
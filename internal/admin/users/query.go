@@ -0,0 +1,19 @@
+package adminusers
+
+import "github.com/ddessilvestri/ecommerce-go/tools"
+
+// QuerySpec declares the user fields the admin listing can sort, filter and select on
+var QuerySpec = tools.QuerySpec{
+	Fields: map[string]tools.Field{
+		"uuid":       {Column: "User_UUID", JSON: "uuid", Sortable: true, Filterable: true},
+		"email":      {Column: "User_Email", JSON: "email", Sortable: true, Filterable: true},
+		"first_name": {Column: "User_FirstName", JSON: "firstName", Sortable: true, Filterable: true},
+		"last_name":  {Column: "User_LastName", JSON: "lastName", Sortable: true, Filterable: true},
+		"status":     {Column: "User_Status", JSON: "status", Type: tools.IntField, Sortable: true, Filterable: true},
+		"date_add":   {Column: "User_DateAdd", JSON: "dateAdd", Type: tools.DateField, Sortable: true, Filterable: true},
+		"date_upg":   {Column: "User_DateUpg", JSON: "dateUpg", Type: tools.DateField, Sortable: true, Filterable: true},
+	},
+	IdColumn:     "User_UUID",
+	DefaultSort:  "uuid",
+	DefaultOrder: "ASC",
+}
@@ -2,10 +2,10 @@ package adminusers
 
 import (
 	"database/sql"
-	"fmt"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/ddessilvestri/ecommerce-go/tools"
 )
 
 type repositorySQL struct {
@@ -31,29 +31,11 @@ func (r *repositorySQL) Delete(uuid string) error {
 	return nil
 }
 
-func (r *repositorySQL) GetAll(offset, limit int, sortBy, order string) ([]models.User, error) {
-	allowedSorts := map[string]string{
-		"uuid":       "User_UUID",
-		"email":      "User_Email",
-		"first_name": "User_FirstName",
-		"last_name":  "User_LastName",
-		"status":     "User_Status",
-		"date_add":   "User_DateAdd",
-		"date_upg":   "User_DateUpg",
-	}
-
-	dbSortBy, ok := allowedSorts[sortBy]
-	if !ok {
-		dbSortBy = "User_UUID"
-	}
-
-	queryBuilder := squirrel.
+func (r *repositorySQL) GetAll(q tools.Query) ([]models.User, error) {
+	queryBuilder := q.ApplyTo(squirrel.
 		Select("User_UUID", "User_Email", "User_FirstName", "User_LastName", "User_Status", "User_DateAdd", "User_DateUpg").
 		From("users").
-		OrderBy(fmt.Sprintf("%s %s", dbSortBy, order)).
-		Offset(uint64(offset)).
-		Limit(uint64(limit)).
-		PlaceholderFormat(squirrel.Question)
+		PlaceholderFormat(squirrel.Question))
 
 	query, args, err := queryBuilder.ToSql()
 	if err != nil {
@@ -86,3 +68,18 @@ func (r *repositorySQL) GetAll(offset, limit int, sortBy, order string) ([]model
 
 	return users, nil
 }
+
+func (r *repositorySQL) Count(q tools.Query) (int, error) {
+	query, args, err := q.ApplyFiltersTo(squirrel.
+		Select("COUNT(*)").
+		From("users").
+		PlaceholderFormat(squirrel.Question)).
+		ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	var total int
+	err = r.db.QueryRow(query, args...).Scan(&total)
+	return total, err
+}
@@ -2,10 +2,10 @@ package adminusers
 
 import (
 	"database/sql"
-	"net/http"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/ddessilvestri/ecommerce-go/routers/route"
 	"github.com/ddessilvestri/ecommerce-go/tools"
 )
 
@@ -20,8 +20,15 @@ func NewRouter(db *sql.DB) *Router {
 	return &Router{handler: handler}
 }
 
+// RegisterRoutes declares the admin users endpoints on the route table
+func RegisterRoutes(table *route.Table, db *sql.DB) {
+	r := NewRouter(db)
+	table.Handle(route.GET, "/admin/users", r.Get).Require(models.RoleAdmin)
+	table.Handle(route.DELETE, "/admin/users/{id}", r.Delete).Require(models.RoleAdmin)
+}
+
 func (r *Router) Post(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
-	return tools.CreateAPIResponse(http.StatusMethodNotAllowed, "not implemented")
+	return tools.ErrorResponse(requestWithContext, models.NewMethodNotAllowedError("not implemented"))
 }
 
 func (r *Router) Get(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
@@ -29,9 +36,13 @@ func (r *Router) Get(requestWithContext models.RequestWithContext) *events.APIGa
 }
 
 func (r *Router) Put(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
-	return tools.CreateAPIResponse(http.StatusMethodNotAllowed, "not implemented")
+	return tools.ErrorResponse(requestWithContext, models.NewMethodNotAllowedError("not implemented"))
+}
+
+func (r *Router) Patch(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	return tools.ErrorResponse(requestWithContext, models.NewMethodNotAllowedError("not implemented"))
 }
 
 func (r *Router) Delete(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
-	return tools.CreateAPIResponse(http.StatusMethodNotAllowed, "not implemented")
+	return r.handler.Delete(requestWithContext)
 }
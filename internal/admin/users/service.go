@@ -1,9 +1,8 @@
 package adminusers
 
 import (
-	"errors"
-
 	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/ddessilvestri/ecommerce-go/tools"
 )
 
 type Service struct {
@@ -15,16 +14,24 @@ func NewService(repo Storage) *Service {
 }
 
 func (s *Service) Delete(uuid string) error {
-	if uuid == "" {
+	// User_UUID is a char(36)
+	if len(uuid) != 36 {
 		return ErrInvalidUUID
 	}
 
 	return s.repo.Delete(uuid)
 }
 
-func (s *Service) GetAll(page, limit int, sortBy, order string) ([]models.User, error) {
-	offset := (page - 1) * limit
-	return s.repo.GetAll(offset, limit, sortBy, order)
+func (s *Service) GetAll(q tools.Query) (models.Page[models.User], error) {
+	users, err := s.repo.GetAll(q)
+	if err != nil {
+		return models.Page[models.User]{}, err
+	}
+	total, err := s.repo.Count(q)
+	if err != nil {
+		return models.Page[models.User]{}, err
+	}
+	return tools.NewListPage(users, q, total, nil), nil
 }
 
-var ErrInvalidUUID = errors.New("invalid user id")
+var ErrInvalidUUID = models.NewFieldError("id", "invalid user id")
@@ -0,0 +1,173 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ddessilvestri/ecommerce-go/auth"
+	"github.com/ddessilvestri/ecommerce-go/awsgo"
+	"github.com/ddessilvestri/ecommerce-go/db"
+	"github.com/ddessilvestri/ecommerce-go/internal/config"
+	"github.com/ddessilvestri/ecommerce-go/internal/product"
+	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/ddessilvestri/ecommerce-go/routers"
+	"github.com/ddessilvestri/ecommerce-go/routers/route"
+	"github.com/ddessilvestri/ecommerce-go/secretm"
+)
+
+// SecretLoader reads the database secret with the given name
+type SecretLoader func(secretName string) (models.SecretRDSJson, error)
+
+// Connector opens a pool on dbName with the credentials in secret
+type Connector func(secret models.SecretRDSJson, dbName string) (*sql.DB, error)
+
+// App holds the process-wide dependencies reused by warm Lambda invocations
+type App struct {
+	Config *config.EnvConfig
+	Secret models.SecretRDSJson
+	DB     *sql.DB
+	Routes *route.Table
+
+	loadSecret SecretLoader
+	connect    Connector
+	lastCheck  time.Time
+}
+
+var (
+	mu      sync.Mutex
+	current *App
+)
+
+// Get returns the shared container, building it on the first call.
+// A cached pool is pinged at most once per DBHealthCheckInterval; when the ping fails
+// the pool is rebuilt, re-reading the secret if the credentials were rejected.
+func Get(ctx context.Context) (*App, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if current == nil {
+		a, err := build()
+		if err != nil {
+			return nil, err
+		}
+		current = a
+		return current, nil
+	}
+
+	if err := current.checkHealth(ctx); err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
+// Reset drops the cached container so the next Get starts from scratch
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if current != nil && current.DB != nil {
+		current.DB.Close()
+	}
+	current = nil
+}
+
+func build() (*App, error) {
+	awsgo.AWSInit()
+
+	conf, err := config.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("config load failed: %w", err)
+	}
+
+	verifier, err := NewVerifier(conf)
+	if err != nil {
+		return nil, fmt.Errorf("token verifier setup failed: %w", err)
+	}
+	auth.Configure(verifier)
+	if err := product.ConfigureSearch(conf.SearchBackend, conf.SearchIndexTTL); err != nil {
+		return nil, fmt.Errorf("search setup failed: %w", err)
+	}
+	product.ConfigureSuggestions(conf.SuggestCacheTTL)
+
+	a := &App{Config: conf, loadSecret: secretm.GetSecret, connect: db.DbConnectAndReturn}
+	if err := a.reconnect(true); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// NewVerifier builds the token verifier described by the configuration;
+// TOKEN_ISSUER and TOKEN_CLIENT_ID are required
+func NewVerifier(conf *config.EnvConfig) (*auth.Verifier, error) {
+	jwksURL := conf.JWKSURL
+	if jwksURL == "" && conf.TokenIssuer != "" {
+		jwksURL = strings.TrimSuffix(conf.TokenIssuer, "/") + "/.well-known/jwks.json"
+	}
+
+	return auth.NewVerifier(auth.VerifierConfig{
+		Keys: auth.NewKeySet(auth.KeySetConfig{
+			URL:  jwksURL,
+			File: conf.JWKSFile,
+		}),
+		Issuer:   conf.TokenIssuer,
+		ClientID: conf.TokenClientID,
+		TokenUse: conf.TokenUse,
+	})
+}
+
+// checkHealth pings the pool when the last check is older than DBHealthCheckInterval
+// and rebuilds it when the ping fails
+func (a *App) checkHealth(ctx context.Context) error {
+	if time.Since(a.lastCheck) < a.Config.DBHealthCheckInterval {
+		return nil
+	}
+
+	err := a.DB.PingContext(ctx)
+	if err == nil {
+		a.lastCheck = time.Now()
+		return nil
+	}
+
+	fmt.Println("Cached database connection is unhealthy, reconnecting:", err.Error())
+	return a.reconnect(db.IsAuthError(err))
+}
+
+// reconnect opens a fresh pool, optionally fetching the secret again first,
+// and only swaps it in (closing the old one) once the new pool answers
+func (a *App) reconnect(refreshSecret bool) error {
+	if refreshSecret {
+		secret, err := a.loadSecret(a.Config.SecretName)
+		if err != nil {
+			return fmt.Errorf("failed to read secret: %w", err)
+		}
+		a.Secret = secret
+	}
+
+	sqlDB, err := a.connect(a.Secret, a.Config.DBName)
+	if err != nil && !refreshSecret && db.IsAuthError(err) {
+		return a.reconnect(true)
+	}
+	if err != nil {
+		return fmt.Errorf("database connection error: %w", err)
+	}
+
+	db.ConfigurePool(sqlDB,
+		a.Config.DBMaxOpenConns,
+		a.Config.DBMaxIdleConns,
+		a.Config.DBConnMaxLifetime,
+		a.Config.DBConnMaxIdleTime,
+	)
+
+	if a.DB != nil {
+		a.DB.Close()
+	}
+	a.DB = sqlDB
+	// Handlers hold the pool they were built with, so the table follows the pool
+	a.Routes = routers.NewRouteTable(sqlDB)
+	a.lastCheck = time.Now()
+	return nil
+}
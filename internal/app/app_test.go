@@ -0,0 +1,163 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ddessilvestri/ecommerce-go/internal/config"
+	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errAccessDenied = &mysql.MySQLError{Number: 1045, Message: "Access denied for user 'gambit'"}
+
+// fakeConnector opens connections whose Ping fails with pingErr
+type fakeConnector struct {
+	pingErr error
+}
+
+func (c *fakeConnector) Connect(context.Context) (driver.Conn, error) { return &fakeConn{c}, nil }
+func (c *fakeConnector) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct {
+	connector *fakeConnector
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+func (c *fakeConn) Ping(context.Context) error          { return c.connector.pingErr }
+
+// fakeBackend hands out the current password and accepts connections only with it
+type fakeBackend struct {
+	password    string
+	secretReads int
+	connects    []string
+	pools       []*fakeConnector
+}
+
+func (b *fakeBackend) loadSecret(secretName string) (models.SecretRDSJson, error) {
+	b.secretReads++
+	return models.SecretRDSJson{Username: "gambit", Password: b.password}, nil
+}
+
+func (b *fakeBackend) connect(secret models.SecretRDSJson, dbName string) (*sql.DB, error) {
+	b.connects = append(b.connects, secret.Password)
+	if secret.Password != b.password {
+		return nil, errAccessDenied
+	}
+	connector := &fakeConnector{}
+	b.pools = append(b.pools, connector)
+	return sql.OpenDB(connector), nil
+}
+
+// newTestApp connects through backend, as build does on a cold start
+func newTestApp(t *testing.T, backend *fakeBackend) *App {
+	a := &App{
+		Config:     &config.EnvConfig{SecretName: "gambit-db", DBName: "gambit", DBHealthCheckInterval: time.Minute},
+		loadSecret: backend.loadSecret,
+		connect:    backend.connect,
+	}
+	require.NoError(t, a.reconnect(true))
+	t.Cleanup(func() { a.DB.Close() })
+	return a
+}
+
+// Test reconnect swaps in the new pool and closes the old one
+func TestReconnect(t *testing.T) {
+	backend := &fakeBackend{password: "v1"}
+	a := newTestApp(t, backend)
+	old, oldRoutes := a.DB, a.Routes
+
+	require.NoError(t, a.reconnect(false))
+
+	assert.NotSame(t, old, a.DB)
+	assert.NotSame(t, oldRoutes, a.Routes, "route table is rebuilt on the new pool")
+	assert.Error(t, old.Ping(), "old pool must be closed")
+	assert.NoError(t, a.DB.Ping())
+	assert.Equal(t, 1, backend.secretReads)
+}
+
+// Test a failed reconnect keeps serving the previous pool
+func TestReconnectFailureKeepsPool(t *testing.T) {
+	backend := &fakeBackend{password: "v1"}
+	a := newTestApp(t, backend)
+	old := a.DB
+	a.connect = func(models.SecretRDSJson, string) (*sql.DB, error) {
+		return nil, errors.New("connection refused")
+	}
+
+	routes := a.Routes
+	err := a.reconnect(false)
+
+	assert.ErrorContains(t, err, "database connection error")
+	assert.Same(t, old, a.DB)
+	assert.Same(t, routes, a.Routes)
+}
+
+// Test the pool is pinged only once the health check interval has passed
+func TestHealthCheckPing(t *testing.T) {
+	backend := &fakeBackend{password: "v1"}
+	a := newTestApp(t, backend)
+	healthy, routes := a.DB, a.Routes
+
+	backend.pools[0].pingErr = errors.New("connection reset")
+	require.NoError(t, a.checkHealth(context.Background()))
+	assert.Same(t, healthy, a.DB, "no ping within the interval")
+
+	backend.pools[0].pingErr = nil
+	a.lastCheck = time.Time{}
+	require.NoError(t, a.checkHealth(context.Background()))
+	assert.Same(t, healthy, a.DB, "a healthy pool is kept")
+	assert.Same(t, routes, a.Routes, "and so is its route table")
+	assert.WithinDuration(t, time.Now(), a.lastCheck, time.Second)
+}
+
+// Test a failed ping rebuilds the pool with the cached secret
+func TestHealthCheckReconnects(t *testing.T) {
+	backend := &fakeBackend{password: "v1"}
+	a := newTestApp(t, backend)
+	broken := a.DB
+
+	backend.pools[0].pingErr = errors.New("connection reset")
+	a.lastCheck = time.Time{}
+	require.NoError(t, a.checkHealth(context.Background()))
+
+	assert.NotSame(t, broken, a.DB)
+	assert.Len(t, backend.pools, 2)
+	assert.Equal(t, 1, backend.secretReads, "secret is not re-read for a network error")
+}
+
+// Test credentials rejected with MySQL error 1045 on ping trigger a secret refresh
+func TestHealthCheckRefreshesRotatedSecret(t *testing.T) {
+	backend := &fakeBackend{password: "v1"}
+	a := newTestApp(t, backend)
+
+	backend.password = "v2"
+	backend.pools[0].pingErr = errAccessDenied
+	a.lastCheck = time.Time{}
+	require.NoError(t, a.checkHealth(context.Background()))
+
+	assert.Equal(t, 2, backend.secretReads)
+	assert.Equal(t, "v2", a.Secret.Password)
+	assert.Equal(t, []string{"v1", "v2"}, backend.connects)
+}
+
+// Test a connection rejected with 1045 while reusing the cached secret refreshes it and retries once
+func TestReconnectRefreshesSecretOnAccessDenied(t *testing.T) {
+	backend := &fakeBackend{password: "v1"}
+	a := newTestApp(t, backend)
+
+	backend.password = "v2"
+	require.NoError(t, a.reconnect(false))
+
+	assert.Equal(t, 2, backend.secretReads)
+	assert.Equal(t, []string{"v1", "v1", "v2"}, backend.connects)
+	assert.Equal(t, "v2", a.Secret.Password)
+}
@@ -29,20 +29,20 @@ func (h *Handler) Post(requestWithContext models.RequestWithContext) (*events.AP
 	// 1. Try to parse the incoming JSON
 	err := json.Unmarshal([]byte(body), &c)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "Invalid JSON body: "+err.Error()), nil
+		return tools.ErrorResponse(requestWithContext, models.NewValidationError("invalid JSON body").Wrap(err)), nil
 	}
 
 	// 2. Call service to create category
 	id, err := h.service.CreateCategory(c)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "Error : "+err.Error()), nil
+		return tools.ErrorResponse(requestWithContext, err), nil
 	}
 
 	// 3. Return success response
 	return tools.CreateAPIResponse(http.StatusOK, fmt.Sprintf(`{"CategID": %d}`, id)), nil
 }
 
-// Post handles the HTTP POST request to create a category
+// Put handles the HTTP PUT request to replace a category
 func (h *Handler) Put(requestWithContext models.RequestWithContext) (*events.APIGatewayProxyResponse, error) {
 	var c models.Category
 	body := requestWithContext.RequestBody()
@@ -50,14 +50,14 @@ func (h *Handler) Put(requestWithContext models.RequestWithContext) (*events.API
 	// 1. Try to parse the incoming JSON
 	err := json.Unmarshal([]byte(body), &c)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "Invalid JSON body: "+err.Error()), nil
+		return tools.ErrorResponse(requestWithContext, models.NewValidationError("invalid JSON body").Wrap(err)), nil
 	}
 
 	// 2. Try to parse the incoming id
 	id := requestWithContext.RequestPathParameters()["id"]
 	idn, err := strconv.Atoi(id)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "Invalid CategoryId: "+err.Error()), nil
+		return tools.ErrorResponse(requestWithContext, models.NewFieldError("id", "invalid category Id").Wrap(err)), nil
 	}
 
 	c.CategID = idn
@@ -65,34 +65,80 @@ func (h *Handler) Put(requestWithContext models.RequestWithContext) (*events.API
 	// 3. Call service to update category
 	err = h.service.UpdateCategory(c)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "Error : "+err.Error()), nil
+		return tools.ErrorResponse(requestWithContext, err), nil
 	}
 
-	// 3. Return success response
+	// 4. Return success response
 	return tools.CreateAPIResponse(http.StatusOK, fmt.Sprintf(`{"Updated CategID": %d}`, idn)), nil
 }
 
-// Post handles the HTTP DELETE request to delete a category
+// Patch handles the HTTP PATCH request: a JSON Merge Patch of the category
+func (h *Handler) Patch(requestWithContext models.RequestWithContext) (*events.APIGatewayProxyResponse, error) {
+
+	// 1. Try to parse the incoming id
+	id := requestWithContext.RequestPathParameters()["id"]
+	idn, err := strconv.Atoi(id)
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, models.NewFieldError("id", "invalid category Id").Wrap(err)), nil
+	}
+
+	// 2. Parse the merge patch document
+	var c models.CategoryPatch
+	if err := tools.ParseMergePatch(requestWithContext.RequestBody(), &c); err != nil {
+		return tools.ErrorResponse(requestWithContext, err), nil
+	}
+
+	// 3. Call service to patch category
+	err = h.service.PatchCategory(idn, c)
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, err), nil
+	}
+
+	// 4. Return success response
+	return tools.CreateAPIResponse(http.StatusOK, fmt.Sprintf(`{"Updated CategID": %d}`, idn)), nil
+}
+
+// Delete handles DELETE /category/{id}[?reassignTo=<id>|?archiveProducts=true]
 func (h *Handler) Delete(requestWithContext models.RequestWithContext) (*events.APIGatewayProxyResponse, error) {
 
 	// 1. Try to parse the incoming id
 	id := requestWithContext.RequestPathParameters()["id"]
 	idn, err := strconv.Atoi(id)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "Invalid CategoryId: "+err.Error()), nil
+		return tools.ErrorResponse(requestWithContext, models.NewFieldError("id", "invalid category Id").Wrap(err)), nil
+	}
+
+	// 2. Read what happens to the products still in the category
+	var opts models.CategoryDelete
+	query := requestWithContext.RequestQueryStringParameters()
+	if val := query["reassignTo"]; val != "" {
+		if opts.ReassignTo, err = strconv.Atoi(val); err != nil {
+			return tools.ErrorResponse(requestWithContext, models.NewFieldError("reassignTo", "invalid 'reassignTo' parameter").Wrap(err)), nil
+		}
+	}
+	if val := query["archiveProducts"]; val != "" {
+		if opts.ArchiveProducts, err = strconv.ParseBool(val); err != nil {
+			return tools.ErrorResponse(requestWithContext, models.NewFieldError("archiveProducts", "invalid 'archiveProducts' parameter").Wrap(err)), nil
+		}
 	}
 
-	// 2. Call service to update category
-	err = h.service.DeleteCategory(idn)
+	// 3. Call service to delete category
+	moved, err := h.service.DeleteCategory(idn, opts)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "Error : "+err.Error()), nil
+		return tools.ErrorResponse(requestWithContext, err), nil
 	}
 
-	// 3. Return success response
+	// 4. Return success response
+	switch {
+	case opts.ReassignTo != 0:
+		return tools.CreateAPIResponse(http.StatusOK, fmt.Sprintf(`{"Deleted CategID": %d, "reassignedProducts": %d}`, idn, moved)), nil
+	case opts.ArchiveProducts:
+		return tools.CreateAPIResponse(http.StatusOK, fmt.Sprintf(`{"Deleted CategID": %d, "archivedProducts": %d}`, idn, moved)), nil
+	}
 	return tools.CreateAPIResponse(http.StatusOK, fmt.Sprintf(`{"Deleted CategID": %d}`, idn)), nil
 }
 
-// Post handles the HTTP DELETE request to delete a category
+// Get handles GET /category[?id=<id>|?slug=<slug>][&stats=true]: one category, or all of them
 func (h *Handler) Get(requestWithContext models.RequestWithContext) (*events.APIGatewayProxyResponse, error) {
 
 	// 1 - First check if id is a query string parameter
@@ -103,18 +149,18 @@ func (h *Handler) Get(requestWithContext models.RequestWithContext) (*events.API
 		// 1. Try to parse the incoming idstr
 		idn, err := strconv.Atoi(idstr)
 		if err != nil {
-			return tools.CreateAPIResponse(http.StatusBadRequest, "Invalid CategoryId: "+err.Error()), nil
+			return tools.ErrorResponse(requestWithContext, models.NewFieldError("id", "invalid category Id").Wrap(err)), nil
 		}
 
-		// 2. Call service to update category
+		// 2. Call service to get the category
 		c, err := h.service.GetCategory(idn)
 		if err != nil {
-			return tools.CreateAPIResponse(http.StatusBadRequest, "Error : "+err.Error()), nil
+			return tools.ErrorResponse(requestWithContext, err), nil
 		}
 
 		body, err := json.Marshal(c)
 		if err != nil {
-			return tools.CreateAPIResponse(http.StatusBadRequest, "Error converting model to json body: "+err.Error()), nil
+			return tools.ErrorResponse(requestWithContext, err), nil
 		}
 
 		// 3. Return success response
@@ -123,34 +169,139 @@ func (h *Handler) Get(requestWithContext models.RequestWithContext) (*events.API
 	} else if slug != "" {
 		// 2 - Second check if slug is a query string parameter
 
-		// 1. Call service to update category
+		// 1. Call service to get the category
 		c, err := h.service.GetCategoryBySlug(slug)
 		if err != nil {
-			return tools.CreateAPIResponse(http.StatusBadRequest, "Error : "+err.Error()), nil
+			return tools.ErrorResponse(requestWithContext, err), nil
 		}
 
 		body, err := json.Marshal(c)
 		if err != nil {
-			return tools.CreateAPIResponse(http.StatusBadRequest, "Error converting model to json body: "+err.Error()), nil
+			return tools.ErrorResponse(requestWithContext, err), nil
 		}
 
-		// 3. Return success response
+		// 2. Return success response
 		return tools.CreateAPIResponse(http.StatusOK, string(body)), nil
 
 	}
 
 	// 3  - Third retrieve all rows
-	categories, err := h.service.GetCategories()
+	withStats, err := parseStats(requestWithContext)
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, err), nil
+	}
+	categories, err := h.service.GetCategories(withStats)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "Error : "+err.Error()), nil
+		return tools.ErrorResponse(requestWithContext, err), nil
 	}
 
 	body, err := json.Marshal(categories)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "Error converting model to json body: "+err.Error()), nil
+		return tools.ErrorResponse(requestWithContext, err), nil
 	}
 
 	// 3. Return success response
 	return tools.CreateAPIResponse(http.StatusOK, string(body)), nil
 
 }
+
+// Tree handles GET /category/tree[?stats=true]: every category nested under its parent
+func (h *Handler) Tree(requestWithContext models.RequestWithContext) (*events.APIGatewayProxyResponse, error) {
+	withStats, err := parseStats(requestWithContext)
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, err), nil
+	}
+
+	tree, err := h.service.GetTree(withStats)
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, err), nil
+	}
+	return jsonResponse(requestWithContext, tree), nil
+}
+
+// Subtree handles GET /category/{id}/tree[?stats=true]: the category with its descendants
+func (h *Handler) Subtree(requestWithContext models.RequestWithContext) (*events.APIGatewayProxyResponse, error) {
+	idn, err := strconv.Atoi(requestWithContext.RequestPathParameters()["id"])
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, models.NewFieldError("id", "invalid category Id").Wrap(err)), nil
+	}
+
+	withStats, err := parseStats(requestWithContext)
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, err), nil
+	}
+
+	subtree, err := h.service.GetSubtree(idn, withStats)
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, err), nil
+	}
+	return jsonResponse(requestWithContext, subtree), nil
+}
+
+// Breadcrumb handles GET /category/{id}/breadcrumb: the categories from the top level down to this one
+func (h *Handler) Breadcrumb(requestWithContext models.RequestWithContext) (*events.APIGatewayProxyResponse, error) {
+	idn, err := strconv.Atoi(requestWithContext.RequestPathParameters()["id"])
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, models.NewFieldError("id", "invalid category Id").Wrap(err)), nil
+	}
+
+	breadcrumb, err := h.service.GetBreadcrumb(idn)
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, err), nil
+	}
+	return jsonResponse(requestWithContext, breadcrumb), nil
+}
+
+// ProductBreadcrumb handles GET /product/{id}/breadcrumb: the breadcrumb of the product's category
+func (h *Handler) ProductBreadcrumb(requestWithContext models.RequestWithContext) (*events.APIGatewayProxyResponse, error) {
+	idn, err := strconv.Atoi(requestWithContext.RequestPathParameters()["id"])
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, models.NewFieldError("id", "invalid product Id").Wrap(err)), nil
+	}
+
+	breadcrumb, err := h.service.GetProductBreadcrumb(idn)
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, err), nil
+	}
+	return jsonResponse(requestWithContext, breadcrumb), nil
+}
+
+// Move handles POST /category/{id}/move with {"parentID": 3, "position": 0}; a null parentID moves to the top level
+func (h *Handler) Move(requestWithContext models.RequestWithContext) (*events.APIGatewayProxyResponse, error) {
+	idn, err := strconv.Atoi(requestWithContext.RequestPathParameters()["id"])
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, models.NewFieldError("id", "invalid category Id").Wrap(err)), nil
+	}
+
+	var move models.CategoryMove
+	if err := json.Unmarshal([]byte(requestWithContext.RequestBody()), &move); err != nil {
+		return tools.ErrorResponse(requestWithContext, models.NewValidationError("invalid JSON body").Wrap(err)), nil
+	}
+
+	if err := h.service.MoveCategory(idn, move); err != nil {
+		return tools.ErrorResponse(requestWithContext, err), nil
+	}
+
+	return tools.CreateAPIResponse(http.StatusOK, fmt.Sprintf(`{"Moved CategID": %d}`, idn)), nil
+}
+
+// parseStats reads ?stats=true, which adds product counts and price ranges to each category
+func parseStats(requestWithContext models.RequestWithContext) (bool, error) {
+	val := requestWithContext.RequestQueryStringParameters()["stats"]
+	if val == "" {
+		return false, nil
+	}
+	withStats, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, models.NewFieldError("stats", "invalid 'stats' parameter").Wrap(err)
+	}
+	return withStats, nil
+}
+
+func jsonResponse(requestWithContext models.RequestWithContext, v interface{}) *events.APIGatewayProxyResponse {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, err)
+	}
+	return tools.CreateAPIResponse(http.StatusOK, string(body))
+}
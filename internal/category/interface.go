@@ -5,8 +5,19 @@ import "github.com/ddessilvestri/ecommerce-go/models"
 type Storage interface {
 	InsertCategory(c models.Category) (int64, error)
 	UpdateCategory(c models.Category) error
-	DeleteCategory(id int) error
+	PatchCategory(id int, c models.CategoryPatch) error
+	CategoryExists(id int) bool
+	DeleteCategory(id int, opts models.CategoryDelete) (int64, error)
 	GetCategory(id int) (models.Category, error)
 	GetCategories() ([]models.Category, error)
-	GetCategoryBySlug(slug string) ([]models.Category, error)
+	GetCategoriesWithStats() ([]models.Category, error)
+	GetCategoryBySlug(slug string) (models.Category, error)
+	GetSubtree(id int) ([]models.Category, error)
+	GetAncestors(id int) ([]models.Category, error)
+	GetProductCategory(productId int) (categoryId int, status string, err error)
+	MoveCategory(id int, move models.CategoryMove, plan MovePlan) error
 }
+
+// MovePlan checks a move against the category's subtree and its new parent (nil at the top level or when
+// it doesn't exist), read with their rows locked, and returns how much the depths of the subtree change
+type MovePlan func(subtree []models.Category, parent *models.Category) (depthDelta int, err error)
@@ -2,8 +2,11 @@ package category
 
 import (
 	"database/sql"
+	"slices"
+	"strings"
 
 	"github.com/Masterminds/squirrel"
+	"github.com/ddessilvestri/ecommerce-go/db"
 	"github.com/ddessilvestri/ecommerce-go/models"
 )
 
@@ -27,8 +30,8 @@ func (r *repositorySQL) InsertCategory(c models.Category) (int64, error) {
 	// Build a safe SQL INSERT query using the squirrel package
 	query, args, err := squirrel.
 		Insert("category").
-		Columns("Categ_Name", "Categ_Path").
-		Values(c.CategName, c.CategPath).
+		Columns("Categ_Name", "Categ_Path", "Categ_ParentId", "Categ_Position", "Categ_Depth").
+		Values(c.CategName, c.CategPath, c.CategParentID, c.CategPosition, c.CategDepth).
 		ToSql()
 
 	if err != nil {
@@ -68,10 +71,19 @@ func (r *repositorySQL) UpdateCategory(c models.Category) error {
 	return nil
 }
 
-func (r *repositorySQL) DeleteCategory(id int) error {
-	// Build a safe SQL UPDATE query using the squirrel package
+// PatchCategory only updates the columns present in the patch
+func (r *repositorySQL) PatchCategory(id int, c models.CategoryPatch) error {
+	columns := map[string]interface{}{}
+	if c.CategName.Set {
+		columns["Categ_Name"] = c.CategName.SQLValue()
+	}
+	if c.CategPath.Set {
+		columns["Categ_Path"] = c.CategPath.SQLValue()
+	}
+
 	query, args, err := squirrel.
-		Delete("category").
+		Update("category").
+		SetMap(columns).
 		Where(squirrel.Eq{"Categ_Id": id}).
 		ToSql()
 
@@ -79,20 +91,114 @@ func (r *repositorySQL) DeleteCategory(id int) error {
 		return err
 	}
 
-	// Execute the query with the generated SQL and arguments
 	_, err = r.db.Exec(query, args...)
+	return err
+}
+
+func (r *repositorySQL) CategoryExists(id int) bool {
+	query, args, err := squirrel.
+		Select("1").
+		From("category").
+		Where(squirrel.Eq{"Categ_Id": id}).
+		Limit(1).
+		ToSql()
+
 	if err != nil {
-		return err
+		return false
 	}
 
-	// Return the updated ID
-	return nil
+	var exists int
+	err = r.db.QueryRow(query, args...).Scan(&exists)
+
+	return err == nil
+}
+
+// DeleteCategory deletes a category in one transaction, after locking it and checking what still
+// references it: child categories always block the delete, products block it unless opts says
+// where they go. It returns how many products were reassigned or archived.
+func (r *repositorySQL) DeleteCategory(id int, opts models.CategoryDelete) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Locking the row (and the matching index ranges below) keeps new children and products
+	// from being attached while we decide. The reassign target is locked with it, in id order,
+	// so it can't be deleted before the products land in it.
+	ids := []int{id}
+	if opts.ReassignTo != 0 {
+		ids = append(ids, opts.ReassignTo)
+	}
+	locked, err := lockCategories(tx, squirrel.Eq{"Categ_Id": ids})
+	if err != nil {
+		return 0, err
+	}
+	found := map[int]bool{}
+	for _, c := range locked {
+		found[c.CategID] = true
+	}
+	if !found[id] {
+		return 0, sql.ErrNoRows
+	}
+	if opts.ReassignTo != 0 && !found[opts.ReassignTo] {
+		return 0, ErrReassignTargetNotFound
+	}
+
+	var children, products int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM category WHERE Categ_ParentId = ? FOR UPDATE", id).Scan(&children); err != nil {
+		return 0, err
+	}
+	if err := tx.QueryRow("SELECT COUNT(*) FROM products WHERE Prod_CategoryId = ? FOR UPDATE", id).Scan(&products); err != nil {
+		return 0, err
+	}
+	if children > 0 || (products > 0 && opts.ReassignTo == 0 && !opts.ArchiveProducts) {
+		return 0, errCategoryInUse(children, products)
+	}
+
+	var moved int64
+	if products > 0 {
+		update := squirrel.Update("products").Where(squirrel.Eq{"Prod_CategoryId": id})
+		if opts.ReassignTo != 0 {
+			update = update.Set("Prod_CategoryId", opts.ReassignTo)
+		} else {
+			update = update.Set("Prod_Status", models.ProductArchived).Set("Prod_CategoryId", nil)
+		}
+
+		query, args, err := update.ToSql()
+		if err != nil {
+			return 0, err
+		}
+		result, err := tx.Exec(query, args...)
+		if err != nil {
+			return 0, err
+		}
+		if moved, err = result.RowsAffected(); err != nil {
+			return 0, err
+		}
+	}
+
+	// Build a safe SQL DELETE query using the squirrel package
+	query, args, err := squirrel.
+		Delete("category").
+		Where(squirrel.Eq{"Categ_Id": id}).
+		ToSql()
+
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(query, args...); err != nil {
+		return 0, err
+	}
+
+	return moved, tx.Commit()
 }
 
 func (r *repositorySQL) GetCategory(id int) (models.Category, error) {
-	// Build a safe SQL UPDATE query using the squirrel package
+	// Build a safe SQL SELECT query using the squirrel package
 	query, args, err := squirrel.
-		Select("Categ_Name", "Categ_Path").
+		Select(categoryColumns...).
 		From("category").
 		Where(squirrel.Eq{"Categ_Id": id}).
 		ToSql()
@@ -101,93 +207,310 @@ func (r *repositorySQL) GetCategory(id int) (models.Category, error) {
 		return models.Category{}, err
 	}
 
-	var name, path string
 	// Execute the query with the generated SQL and arguments
-	row := r.db.QueryRow(query, args...)
-	if err := row.Scan(&name, &path); err != nil {
-		return models.Category{}, err
-	}
-
-	return models.Category{
-		CategID:   id,
-		CategName: name,
-		CategPath: path,
-	}, nil
-
+	return scanCategory(r.db.QueryRow(query, args...))
 }
+
+// GetCategories returns every category, parents before children and siblings in position order
 func (r *repositorySQL) GetCategories() ([]models.Category, error) {
-	// Build a safe SQL UPDATE query using the squirrel package
 	query, args, err := squirrel.
-		Select("Categ_Id", "Categ_Name", "Categ_Path").
+		Select(categoryColumns...).
 		From("category").
+		OrderBy("Categ_Depth", "Categ_ParentId", "Categ_Position", "Categ_Name").
 		ToSql()
 
 	if err != nil {
 		return []models.Category{}, err
 	}
 
-	var categories []models.Category
-	var id int
-	var name, path string
-	// Execute the query with the generated SQL and arguments
-	rows, err := r.db.Query(query, args...)
+	return r.queryCategories(query, args...)
+}
 
+// GetCategoriesWithStats returns every category with the product figures of the category itself
+// and of its whole subtree, in one grouped query: the recursive closure pairs each category with
+// itself and all its descendants, then the active products of those descendants are aggregated
+// per ancestor. Only the rows where descendant = ancestor count towards the category's own figures.
+func (r *repositorySQL) GetCategoriesWithStats() ([]models.Category, error) {
+	query := `WITH RECURSIVE closure AS (
+		SELECT Categ_Id AS ancestor, Categ_Id AS descendant FROM category
+		UNION ALL
+		SELECT closure.ancestor, c.Categ_Id FROM closure JOIN category c ON c.Categ_ParentId = closure.descendant
+	)
+	SELECT ` + childColumnList + `,
+		COUNT(CASE WHEN closure.descendant = c.Categ_Id THEN p.Prod_Id END),
+		COUNT(CASE WHEN closure.descendant = c.Categ_Id AND p.Prod_Stock > 0 THEN p.Prod_Id END),
+		MIN(CASE WHEN closure.descendant = c.Categ_Id THEN p.Prod_Price END),
+		MAX(CASE WHEN closure.descendant = c.Categ_Id THEN p.Prod_Price END),
+		COUNT(p.Prod_Id),
+		COUNT(CASE WHEN p.Prod_Stock > 0 THEN p.Prod_Id END),
+		MIN(p.Prod_Price),
+		MAX(p.Prod_Price)
+	FROM category c
+	JOIN closure ON closure.ancestor = c.Categ_Id
+	LEFT JOIN products p ON p.Prod_CategoryId = closure.descendant AND p.Prod_Status = ?
+	GROUP BY c.Categ_Id
+	ORDER BY c.Categ_Depth, c.Categ_ParentId, c.Categ_Position, c.Categ_Name`
+
+	rows, err := r.db.Query(query, models.ProductActive)
 	if err != nil {
 		return []models.Category{}, err
 	}
 	defer rows.Close()
 
+	categories := []models.Category{}
 	for rows.Next() {
-		if err := rows.Scan(&id, &name, &path); err != nil {
+		var own, total models.CategoryStats
+		var ownMin, ownMax, totalMin, totalMax sql.NullFloat64
+		c, err := scanCategory(rows,
+			&own.Products, &own.InStock, &ownMin, &ownMax,
+			&total.Products, &total.InStock, &totalMin, &totalMax)
+		if err != nil {
 			return []models.Category{}, err
 		}
-		categories = append(categories, models.Category{
-			CategID:   id,
-			CategName: name,
-			CategPath: path,
-		})
+		own.MinPrice, own.MaxPrice = nullablePrice(ownMin), nullablePrice(ownMax)
+		total.MinPrice, total.MaxPrice = nullablePrice(totalMin), nullablePrice(totalMax)
+		c.Stats, c.TotalStats = &own, &total
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
 
+func nullablePrice(price sql.NullFloat64) *float64 {
+	if !price.Valid {
+		return nil
 	}
+	return &price.Float64
+}
 
-	return categories, nil
+// GetCategoryBySlug returns the category whose path is exactly slug
+func (r *repositorySQL) GetCategoryBySlug(slug string) (models.Category, error) {
+	query, args, err := squirrel.
+		Select(categoryColumns...).
+		From("category").
+		Where(squirrel.Eq{"Categ_Path": slug}).
+		Limit(1).
+		ToSql()
 
+	if err != nil {
+		return models.Category{}, err
+	}
+
+	return scanCategory(r.db.QueryRow(query, args...))
 }
 
-func (r *repositorySQL) GetCategoryBySlug(slug string) ([]models.Category, error) {
-	// Build a safe SQL UPDATE query using the squirrel package
+// GetSubtree returns the category and all its descendants, parents before children
+func (r *repositorySQL) GetSubtree(id int) ([]models.Category, error) {
+	query := `WITH RECURSIVE subtree AS (
+		SELECT ` + categoryColumnList + ` FROM category WHERE Categ_Id = ?
+		UNION ALL
+		SELECT ` + childColumnList + ` FROM category c JOIN subtree s ON c.Categ_ParentId = s.Categ_Id
+	)
+	SELECT ` + categoryColumnList + ` FROM subtree ORDER BY Categ_Depth, Categ_ParentId, Categ_Position, Categ_Name`
+
+	return r.queryCategories(query, id)
+}
+
+// GetAncestors returns the path from the top level down to the category itself
+func (r *repositorySQL) GetAncestors(id int) ([]models.Category, error) {
+	query := `WITH RECURSIVE ancestors AS (
+		SELECT ` + categoryColumnList + ` FROM category WHERE Categ_Id = ?
+		UNION ALL
+		SELECT ` + childColumnList + ` FROM category c JOIN ancestors a ON c.Categ_Id = a.Categ_ParentId
+	)
+	SELECT ` + categoryColumnList + ` FROM ancestors ORDER BY Categ_Depth`
+
+	return r.queryCategories(query, id)
+}
+
+// GetProductCategory returns the category a product belongs to, and the status of the product
+func (r *repositorySQL) GetProductCategory(productId int) (int, string, error) {
+	query, args, err := squirrel.
+		Select("Prod_CategoryId", "Prod_Status").
+		From("products").
+		Where(squirrel.Eq{"Prod_Id": productId}).
+		ToSql()
+
+	if err != nil {
+		return 0, "", err
+	}
+
+	var categoryId sql.NullInt64
+	var status string
+	if err := r.db.QueryRow(query, args...).Scan(&categoryId, &status); err != nil {
+		return 0, "", err
+	}
+	return int(categoryId.Int64), status, nil
+}
+
+// MoveCategory re-parents a category and shifts the depths of its subtree in one transaction.
+// The subtree and the new parent are read with their rows locked FOR UPDATE and handed to plan,
+// so a concurrent move can't change them between the cycle check and the write.
+func (r *repositorySQL) MoveCategory(id int, move models.CategoryMove, plan MovePlan) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	subtree, err := lockSubtree(tx, id)
+	if err != nil {
+		return movedErr(err)
+	}
+	ids := make([]int, 0, len(subtree))
+	for _, c := range subtree {
+		ids = append(ids, c.CategID)
+	}
+
+	var parent *models.Category
+	if move.ParentID != nil && !slices.Contains(ids, *move.ParentID) {
+		locked, err := lockCategories(tx, squirrel.Eq{"Categ_Id": *move.ParentID})
+		if err != nil {
+			return movedErr(err)
+		}
+		if len(locked) == 1 {
+			parent = &locked[0]
+		}
+	}
+
+	depthDelta, err := plan(subtree, parent)
+	if err != nil {
+		return err
+	}
+
+	query, args, err := squirrel.
+		Update("category").
+		Set("Categ_ParentId", move.ParentID).
+		Set("Categ_Position", move.Position).
+		Where(squirrel.Eq{"Categ_Id": id}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(query, args...); err != nil {
+		return movedErr(err)
+	}
+
+	if depthDelta != 0 {
+		query, args, err = squirrel.
+			Update("category").
+			Set("Categ_Depth", squirrel.Expr("Categ_Depth + ?", depthDelta)).
+			Where(squirrel.Eq{"Categ_Id": ids}).
+			ToSql()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(query, args...); err != nil {
+			return movedErr(err)
+		}
+	}
+
+	return movedErr(tx.Commit())
+}
+
+// lockSubtree reads a category and all its descendants level by level, locking each row.
+// A recursive CTE can't be read FOR UPDATE, so the levels are walked from the application;
+// the tree is at most maxCategoryLevels deep.
+func lockSubtree(tx *sql.Tx, id int) ([]models.Category, error) {
+	subtree, err := lockCategories(tx, squirrel.Eq{"Categ_Id": id})
+	if err != nil || len(subtree) == 0 {
+		return subtree, err
+	}
+	level := []int{id}
+	for len(level) > 0 {
+		children, err := lockCategories(tx, squirrel.Eq{"Categ_ParentId": level})
+		if err != nil {
+			return nil, err
+		}
+		level = level[:0]
+		for _, c := range children {
+			level = append(level, c.CategID)
+		}
+		subtree = append(subtree, children...)
+	}
+	return subtree, nil
+}
+
+// lockCategories reads the categories matching where with their rows locked until the transaction ends
+func lockCategories(tx *sql.Tx, where squirrel.Sqlizer) ([]models.Category, error) {
 	query, args, err := squirrel.
-		Select("Categ_Id", "Categ_Name", "Categ_Path").
+		Select(categoryColumns...).
 		From("category").
-		Where(squirrel.Like{"Categ_Path": "%" + slug + "%"}).
+		Where(where).
+		OrderBy("Categ_Id").
+		Suffix("FOR UPDATE").
 		ToSql()
+	if err != nil {
+		return nil, err
+	}
 
+	rows, err := tx.Query(query, args...)
 	if err != nil {
-		return []models.Category{}, err
+		return nil, err
 	}
+	defer rows.Close()
 
 	var categories []models.Category
-	var id int
-	var name, path string
-	// Execute the query with the generated SQL and arguments
-	rows, err := r.db.Query(query, args...)
+	for rows.Next() {
+		c, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+// movedErr reports a deadlock between two concurrent moves as a conflict the client can retry
+func movedErr(err error) error {
+	if db.IsDeadlock(err) {
+		return ErrConcurrentMove
+	}
+	return err
+}
+
+// categoryColumns are read by every category query, in the order scanCategory expects
+var categoryColumns = []string{"Categ_Id", "Categ_Name", "Categ_Path", "Categ_ParentId", "Categ_Position", "Categ_Depth"}
+
+var categoryColumnList = strings.Join(categoryColumns, ", ")
+var childColumnList = "c." + strings.Join(categoryColumns, ", c.")
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
 
+// scanCategory reads categoryColumns, then any extra column; name, path and parent are nullable
+func scanCategory(row rowScanner, extra ...interface{}) (models.Category, error) {
+	var c models.Category
+	var name, path sql.NullString
+	var parent sql.NullInt64
+	dest := []interface{}{&c.CategID, &name, &path, &parent, &c.CategPosition, &c.CategDepth}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return models.Category{}, err
+	}
+	c.CategName = name.String
+	c.CategPath = path.String
+	if parent.Valid {
+		parentId := int(parent.Int64)
+		c.CategParentID = &parentId
+	}
+	return c, nil
+}
+
+func (r *repositorySQL) queryCategories(query string, args ...interface{}) ([]models.Category, error) {
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return []models.Category{}, err
 	}
 	defer rows.Close()
 
+	categories := []models.Category{}
 	for rows.Next() {
-		if err := rows.Scan(&id, &name, &path); err != nil {
+		c, err := scanCategory(rows)
+		if err != nil {
 			return []models.Category{}, err
 		}
-		categories = append(categories, models.Category{
-			CategID:   id,
-			CategName: name,
-			CategPath: path,
-		})
-
+		categories = append(categories, c)
 	}
-
-	return categories, nil
-
+	return categories, rows.Err()
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/ddessilvestri/ecommerce-go/routers/route"
 )
 
 // Router struct contains all dependencies
@@ -20,6 +21,21 @@ func NewRouter(db *sql.DB) *Router {
 	return &Router{handler: handler}
 }
 
+// RegisterRoutes declares the category endpoints on the route table
+func RegisterRoutes(table *route.Table, db *sql.DB) {
+	r := NewRouter(db)
+	table.Handle(route.GET, "/category", r.Get).AllowAnonymous()
+	table.Handle(route.POST, "/category", r.Post).Require(models.RoleAdmin)
+	table.Handle(route.PUT, "/category/{id}", r.Put).Require(models.RoleAdmin)
+	table.Handle(route.PATCH, "/category/{id}", r.Patch).Require(models.RoleAdmin)
+	table.Handle(route.DELETE, "/category/{id}", r.Delete).Require(models.RoleAdmin)
+	table.Handle(route.GET, "/category/tree", r.Tree).AllowAnonymous()
+	table.Handle(route.GET, "/category/{id}/tree", r.Subtree).AllowAnonymous()
+	table.Handle(route.GET, "/category/{id}/breadcrumb", r.Breadcrumb).AllowAnonymous()
+	table.Handle(route.GET, "/product/{id}/breadcrumb", r.ProductBreadcrumb).AllowAnonymous()
+	table.Handle(route.POST, "/category/{id}/move", r.Move).Require(models.RoleAdmin)
+}
+
 // Implements the EntityRouter interface
 
 func (r *Router) Post(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
@@ -38,7 +54,37 @@ func (r *Router) Put(requestWithContext models.RequestWithContext) *events.APIGa
 	return resp
 }
 
+func (r *Router) Patch(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	resp, _ := r.handler.Patch(requestWithContext)
+	return resp
+}
+
 func (r *Router) Delete(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
 	resp, _ := r.handler.Delete(requestWithContext)
 	return resp
 }
+
+func (r *Router) Tree(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	resp, _ := r.handler.Tree(requestWithContext)
+	return resp
+}
+
+func (r *Router) Subtree(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	resp, _ := r.handler.Subtree(requestWithContext)
+	return resp
+}
+
+func (r *Router) Breadcrumb(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	resp, _ := r.handler.Breadcrumb(requestWithContext)
+	return resp
+}
+
+func (r *Router) ProductBreadcrumb(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	resp, _ := r.handler.ProductBreadcrumb(requestWithContext)
+	return resp
+}
+
+func (r *Router) Move(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	resp, _ := r.handler.Move(requestWithContext)
+	return resp
+}
@@ -1,7 +1,11 @@
 package category
 
 import (
+	"database/sql"
 	"errors"
+	"fmt"
+	"slices"
+	"sort"
 
 	"github.com/ddessilvestri/ecommerce-go/models"
 )
@@ -23,7 +27,19 @@ func (s *Service) CreateCategory(c models.Category) (int64, error) {
 		return 0, ErrInvalidCategory
 	}
 
-	// Business logic: could include auditing, formatting, etc.
+	// The depth follows from the parent, whatever the client sent
+	c.CategDepth = 0
+	if c.CategParentID != nil {
+		parent, err := s.parent(*c.CategParentID)
+		if err != nil {
+			return 0, err
+		}
+		c.CategDepth = parent.CategDepth + 1
+		if c.CategDepth >= maxCategoryLevels {
+			return 0, ErrCategoryTooDeep
+		}
+	}
+
 	return s.repo.InsertCategory(c)
 }
 
@@ -41,15 +57,45 @@ func (s *Service) UpdateCategory(c models.Category) error {
 
 }
 
-// DeleteCategory performs validation and delegates to the repository.
-func (s *Service) DeleteCategory(id int) error {
-	// Simple validation (can be more elaborate in real use cases)
+// PatchCategory applies a JSON Merge Patch; name and path can be changed but not cleared
+func (s *Service) PatchCategory(id int, c models.CategoryPatch) error {
 	if id < 1 {
 		return ErrInvalidCategoryId
 	}
-	// Business logic: could include auditing, formatting, etc.
-	return s.repo.DeleteCategory(id)
+	if c.CategName.Set && (c.CategName.Null || c.CategName.Value == "") {
+		return ErrInvalidCategory
+	}
+	if c.CategPath.Set && (c.CategPath.Null || c.CategPath.Value == "") {
+		return ErrInvalidCategory
+	}
+	if !s.repo.CategoryExists(id) {
+		return ErrCategoryNotFound
+	}
+	return s.repo.PatchCategory(id, c)
+}
 
+// DeleteCategory refuses to delete a category that still has subcategories, or products when
+// opts gives them nowhere to go; otherwise the products are reassigned or archived along with the delete.
+// It returns how many products were reassigned or archived.
+func (s *Service) DeleteCategory(id int, opts models.CategoryDelete) (int64, error) {
+	// Simple validation (can be more elaborate in real use cases)
+	if id < 1 {
+		return 0, ErrInvalidCategoryId
+	}
+	if opts.ReassignTo != 0 && opts.ArchiveProducts {
+		return 0, ErrConflictingDeleteOptions
+	}
+	if opts.ReassignTo != 0 {
+		if opts.ReassignTo == id {
+			return 0, ErrReassignToSelf
+		}
+		// Checked again with the row locked in the delete transaction
+		if opts.ReassignTo < 0 || !s.repo.CategoryExists(opts.ReassignTo) {
+			return 0, ErrReassignTargetNotFound
+		}
+	}
+
+	return s.repo.DeleteCategory(id, opts)
 }
 
 // GetCategory by id performs validation and delegates to the repository.
@@ -66,28 +112,246 @@ func (s *Service) GetCategory(id int) (models.Category, error) {
 
 }
 
-// GetCategory by id performs validation and delegates to the repository.
-func (s *Service) GetCategories() ([]models.Category, error) {
-
+// GetCategories returns every category, with product figures when withStats is set
+func (s *Service) GetCategories(withStats bool) ([]models.Category, error) {
+	if withStats {
+		return s.repo.GetCategoriesWithStats()
+	}
 	return s.repo.GetCategories()
 
 }
 
-// GetCategory by id performs validation and delegates to the repository.
-func (s *Service) GetCategoryBySlug(slug string) ([]models.Category, error) {
+// GetCategoryBySlug returns the category whose path is slug
+func (s *Service) GetCategoryBySlug(slug string) (models.Category, error) {
 
 	// Simple validation (can be more elaborate in real use cases)
 	if slug == "" {
-		return []models.Category{}, ErrInvalidCategorySlug
+		return models.Category{}, ErrInvalidCategorySlug
 	}
 
-	// Business logic: could include auditing, formatting, etc.
-
 	return s.repo.GetCategoryBySlug(slug)
 
 }
 
+// GetTree returns every category nested under its parent
+func (s *Service) GetTree(withStats bool) ([]models.Category, error) {
+	categories, err := s.GetCategories(withStats)
+	if err != nil {
+		return nil, err
+	}
+	return BuildTree(categories), nil
+}
+
+// GetSubtree returns the category with its descendants nested under it
+func (s *Service) GetSubtree(id int, withStats bool) (models.Category, error) {
+	if id < 1 {
+		return models.Category{}, ErrInvalidCategoryId
+	}
+
+	// Figures come from one query over every category, the subtree is then cut from the full tree
+	if withStats {
+		tree, err := s.GetTree(true)
+		if err != nil {
+			return models.Category{}, err
+		}
+		if node, ok := findInTree(tree, id); ok {
+			return node, nil
+		}
+		return models.Category{}, ErrCategoryNotFound
+	}
+
+	categories, err := s.repo.GetSubtree(id)
+	if err != nil {
+		return models.Category{}, err
+	}
+	if len(categories) == 0 {
+		return models.Category{}, ErrCategoryNotFound
+	}
+	return BuildTree(categories)[0], nil
+}
+
+// GetBreadcrumb returns the categories from the top level down to id
+func (s *Service) GetBreadcrumb(id int) ([]models.Category, error) {
+	if id < 1 {
+		return nil, ErrInvalidCategoryId
+	}
+	breadcrumb, err := s.repo.GetAncestors(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(breadcrumb) == 0 {
+		return nil, ErrCategoryNotFound
+	}
+	return breadcrumb, nil
+}
+
+// GetProductBreadcrumb returns the breadcrumb of the product's category, empty when it has none.
+// It is public, so products the storefront doesn't show are not found, as with GET /product.
+func (s *Service) GetProductBreadcrumb(productId int) ([]models.Category, error) {
+	if productId < 1 {
+		return nil, ErrInvalidProductId
+	}
+	categoryId, status, err := s.repo.GetProductCategory(productId)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !models.ProductVisible(status)) {
+		return nil, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if categoryId == 0 {
+		return []models.Category{}, nil
+	}
+	return s.GetBreadcrumb(categoryId)
+}
+
+// MoveCategory places a category under a new parent, or at the top level when ParentID is nil.
+// The new parent cannot be the category itself or one of its descendants, which would detach
+// that branch from the tree in a cycle. Depths of the whole subtree follow the move.
+// The checks run inside the repository transaction, on rows locked against concurrent moves.
+func (s *Service) MoveCategory(id int, move models.CategoryMove) error {
+	if id < 1 {
+		return ErrInvalidCategoryId
+	}
+	if move.ParentID != nil && *move.ParentID < 1 {
+		return ErrParentCategoryNotFound
+	}
+
+	return s.repo.MoveCategory(id, move, func(subtree []models.Category, parent *models.Category) (int, error) {
+		return planMove(id, move, subtree, parent)
+	})
+}
+
+// planMove validates moving category id, the root of subtree, under parent and returns the depth change
+func planMove(id int, move models.CategoryMove, subtree []models.Category, parent *models.Category) (int, error) {
+	if len(subtree) == 0 {
+		return 0, ErrCategoryNotFound
+	}
+
+	var node models.Category
+	ids := make([]int, 0, len(subtree))
+	height := 0
+	for _, c := range subtree {
+		ids = append(ids, c.CategID)
+		if c.CategID == id {
+			node = c
+		}
+	}
+	for _, c := range subtree {
+		height = max(height, c.CategDepth-node.CategDepth)
+	}
+
+	depth := 0
+	if move.ParentID != nil {
+		if slices.Contains(ids, *move.ParentID) {
+			return 0, ErrCategoryCycle
+		}
+		if parent == nil {
+			return 0, ErrParentCategoryNotFound
+		}
+		depth = parent.CategDepth + 1
+	}
+	if depth+height >= maxCategoryLevels {
+		return 0, ErrCategoryTooDeep
+	}
+
+	return depth - node.CategDepth, nil
+}
+
+// parent loads the category a child is placed under
+func (s *Service) parent(id int) (models.Category, error) {
+	if id < 1 {
+		return models.Category{}, ErrParentCategoryNotFound
+	}
+	parent, err := s.repo.GetCategory(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Category{}, ErrParentCategoryNotFound
+	}
+	return parent, err
+}
+
+// BuildTree nests categories under their parents; categories whose parent is not in the list
+// are roots, so a subtree builds into a single root. Siblings are ordered by position, then name.
+func BuildTree(categories []models.Category) []models.Category {
+	byParent := map[int][]models.Category{}
+	known := map[int]bool{}
+	for _, c := range categories {
+		known[c.CategID] = true
+	}
+
+	var roots []models.Category
+	for _, c := range categories {
+		if c.CategParentID != nil && known[*c.CategParentID] {
+			byParent[*c.CategParentID] = append(byParent[*c.CategParentID], c)
+		} else {
+			roots = append(roots, c)
+		}
+	}
+
+	var attach func(level []models.Category) []models.Category
+	attach = func(level []models.Category) []models.Category {
+		sort.SliceStable(level, func(i, j int) bool {
+			if level[i].CategPosition != level[j].CategPosition {
+				return level[i].CategPosition < level[j].CategPosition
+			}
+			return level[i].CategName < level[j].CategName
+		})
+		for i := range level {
+			level[i].Children = attach(byParent[level[i].CategID])
+		}
+		return level
+	}
+	return nonNil(attach(roots))
+}
+
+// findInTree returns the node with the given id, searching depth first
+func findInTree(tree []models.Category, id int) (models.Category, bool) {
+	for _, c := range tree {
+		if c.CategID == id {
+			return c, true
+		}
+		if node, ok := findInTree(c.Children, id); ok {
+			return node, true
+		}
+	}
+	return models.Category{}, false
+}
+
+func nonNil(categories []models.Category) []models.Category {
+	if categories == nil {
+		return []models.Category{}
+	}
+	return categories
+}
+
+// errCategoryInUse is the 409 returned when a delete would orphan subcategories or products
+func errCategoryInUse(children, products int) error {
+	message := fmt.Sprintf("category is still used by %d subcategories and %d products", children, products)
+	switch {
+	case children > 0:
+		message += ": move or delete its subcategories first"
+	case products > 0:
+		message += ": pass 'reassignTo=<categoryId>' or 'archiveProducts=true'"
+	}
+	return models.NewConflictError(message)
+}
+
+// maxCategoryLevels bounds the depth of the tree: top-level categories plus nine levels below
+const maxCategoryLevels = 10
+
 // ErrInvalidCategory represents a validation error.
-var ErrInvalidCategory = errors.New("invalid category: name and path are required")
-var ErrInvalidCategoryId = errors.New("invalid category Id: Id < 1 ")
-var ErrInvalidCategorySlug = errors.New("invalid category Slug: empty slug ")
+var ErrInvalidCategory = models.NewValidationError("invalid category: name and path are required",
+	models.FieldError{Field: "categName", Message: "required"},
+	models.FieldError{Field: "categPath", Message: "required"},
+)
+var ErrConcurrentMove = models.NewConflictError("the category tree changed during the move: retry")
+var ErrInvalidCategoryId = models.NewFieldError("id", "invalid category Id: Id < 1")
+var ErrInvalidCategorySlug = models.NewFieldError("slug", "invalid category Slug: empty slug")
+var ErrCategoryNotFound = models.NewNotFoundError("category not found")
+var ErrConflictingDeleteOptions = models.NewValidationError("invalid delete: use either 'reassignTo' or 'archiveProducts', not both")
+var ErrReassignToSelf = models.NewFieldError("reassignTo", "invalid 'reassignTo': products cannot be reassigned to the deleted category")
+var ErrReassignTargetNotFound = models.NewFieldError("reassignTo", "invalid 'reassignTo': category not found")
+var ErrInvalidProductId = models.NewFieldError("id", "invalid product Id: Id < 1")
+var ErrProductNotFound = models.NewNotFoundError("product not found")
+var ErrParentCategoryNotFound = models.NewValidationError("invalid parent: category not found")
+var ErrCategoryCycle = models.NewFieldError("parentID", "invalid parent: a category cannot be moved under itself or one of its descendants")
+var ErrCategoryTooDeep = models.NewValidationError(fmt.Sprintf("invalid parent: categories can be nested at most %d levels deep", maxCategoryLevels))
@@ -0,0 +1,268 @@
+package category
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parentId(id int) *int {
+	return &id
+}
+
+// treeStore keeps a category tree in memory; other Storage methods are not used
+type treeStore struct {
+	Storage
+	categories map[int]models.Category
+
+	moved      *models.CategoryMove
+	movedIds   []int
+	depthDelta int
+	deleted    *models.CategoryDelete
+}
+
+// electronics(1) > audio(2) > headphones(3), computers(4)
+func newTreeStore() *treeStore {
+	return &treeStore{categories: map[int]models.Category{
+		1: {CategID: 1, CategName: "Electronics", CategPath: "electronics"},
+		2: {CategID: 2, CategName: "Audio", CategPath: "audio", CategParentID: parentId(1), CategDepth: 1},
+		3: {CategID: 3, CategName: "Headphones", CategPath: "headphones", CategParentID: parentId(2), CategDepth: 2},
+		4: {CategID: 4, CategName: "Computers", CategPath: "computers"},
+	}}
+}
+
+func (s *treeStore) GetCategory(id int) (models.Category, error) {
+	c, ok := s.categories[id]
+	if !ok {
+		return models.Category{}, sql.ErrNoRows
+	}
+	return c, nil
+}
+
+func (s *treeStore) GetSubtree(id int) ([]models.Category, error) {
+	root, ok := s.categories[id]
+	if !ok {
+		return []models.Category{}, nil
+	}
+	subtree := []models.Category{root}
+	for i := 0; i < len(subtree); i++ {
+		for _, c := range s.categories {
+			if c.CategParentID != nil && *c.CategParentID == subtree[i].CategID {
+				subtree = append(subtree, c)
+			}
+		}
+	}
+	return subtree, nil
+}
+
+func (s *treeStore) GetCategoriesWithStats() ([]models.Category, error) {
+	var categories []models.Category
+	for _, c := range s.categories {
+		c.Stats = &models.CategoryStats{Products: c.CategID}
+		categories = append(categories, c)
+	}
+	return categories, nil
+}
+
+// GetAncestors walks up from id, top level first
+func (s *treeStore) GetAncestors(id int) ([]models.Category, error) {
+	var ancestors []models.Category
+	for c, ok := s.categories[id]; ok; {
+		ancestors = append([]models.Category{c}, ancestors...)
+		if c.CategParentID == nil {
+			break
+		}
+		c, ok = s.categories[*c.CategParentID]
+	}
+	return ancestors, nil
+}
+
+// Products 10 (active), 11 (draft) and 12 (archived) are all filed under headphones(3)
+func (s *treeStore) GetProductCategory(productId int) (int, string, error) {
+	statuses := map[int]string{10: models.ProductActive, 11: models.ProductDraft, 12: models.ProductArchived}
+	status, ok := statuses[productId]
+	if !ok {
+		return 0, "", sql.ErrNoRows
+	}
+	return 3, status, nil
+}
+
+func (s *treeStore) CategoryExists(id int) bool {
+	_, ok := s.categories[id]
+	return ok
+}
+
+func (s *treeStore) DeleteCategory(id int, opts models.CategoryDelete) (int64, error) {
+	s.deleted = &opts
+	return 2, nil
+}
+
+// MoveCategory runs the plan on the stored tree like the repository does under its locks, then applies the move
+func (s *treeStore) MoveCategory(id int, move models.CategoryMove, plan MovePlan) error {
+	subtree, _ := s.GetSubtree(id)
+	var parent *models.Category
+	if move.ParentID != nil {
+		if p, ok := s.categories[*move.ParentID]; ok {
+			parent = &p
+		}
+	}
+	depthDelta, err := plan(subtree, parent)
+	if err != nil {
+		return err
+	}
+
+	s.moved, s.movedIds, s.depthDelta = &move, nil, depthDelta
+	for _, c := range subtree {
+		s.movedIds = append(s.movedIds, c.CategID)
+		c.CategDepth += depthDelta
+		if c.CategID == id {
+			c.CategParentID = move.ParentID
+		}
+		s.categories[c.CategID] = c
+	}
+	return nil
+}
+
+// Test categories nest under their parents, siblings by position then name
+func TestBuildTree(t *testing.T) {
+	tree := BuildTree([]models.Category{
+		{CategID: 1, CategName: "Electronics"},
+		{CategID: 2, CategName: "Audio", CategParentID: parentId(1), CategPosition: 1},
+		{CategID: 3, CategName: "Phones", CategParentID: parentId(1)},
+		{CategID: 4, CategName: "Cameras", CategParentID: parentId(1)},
+		{CategID: 5, CategName: "Books", CategPosition: 2},
+	})
+
+	require.Len(t, tree, 2)
+	assert.Equal(t, "Electronics", tree[0].CategName)
+	assert.Equal(t, "Books", tree[1].CategName)
+
+	var children []string
+	for _, c := range tree[0].Children {
+		children = append(children, c.CategName)
+	}
+	assert.Equal(t, []string{"Cameras", "Phones", "Audio"}, children)
+	assert.Empty(t, tree[1].Children)
+
+	assert.Equal(t, []models.Category{}, BuildTree(nil))
+}
+
+// Test a subtree builds into a single root even though its root has a parent
+func TestBuildSubtree(t *testing.T) {
+	tree := BuildTree([]models.Category{
+		{CategID: 2, CategName: "Audio", CategParentID: parentId(1), CategDepth: 1},
+		{CategID: 3, CategName: "Headphones", CategParentID: parentId(2), CategDepth: 2},
+	})
+	require.Len(t, tree, 1)
+	assert.Equal(t, 3, tree[0].Children[0].CategID)
+}
+
+// Test moving a branch shifts the depth of the whole subtree
+func TestMoveCategory(t *testing.T) {
+	store := newTreeStore()
+	service := NewCategoryService(store)
+
+	require.NoError(t, service.MoveCategory(2, models.CategoryMove{ParentID: parentId(4), Position: 3}))
+	assert.Equal(t, 4, *store.moved.ParentID)
+	assert.ElementsMatch(t, []int{2, 3}, store.movedIds)
+	assert.Equal(t, 0, store.depthDelta)
+
+	require.NoError(t, service.MoveCategory(3, models.CategoryMove{}))
+	assert.Nil(t, store.moved.ParentID)
+	assert.Equal(t, -2, store.depthDelta)
+}
+
+// Test a category cannot be moved under itself or its descendants
+func TestMoveCategoryRejectsCycles(t *testing.T) {
+	service := NewCategoryService(newTreeStore())
+
+	assert.ErrorIs(t, service.MoveCategory(2, models.CategoryMove{ParentID: parentId(2)}), ErrCategoryCycle)
+	assert.ErrorIs(t, service.MoveCategory(1, models.CategoryMove{ParentID: parentId(3)}), ErrCategoryCycle)
+	assert.ErrorIs(t, service.MoveCategory(1, models.CategoryMove{ParentID: parentId(99)}), ErrParentCategoryNotFound)
+	assert.ErrorIs(t, service.MoveCategory(99, models.CategoryMove{}), ErrCategoryNotFound)
+}
+
+// Test the second of two crossing moves sees the first one and is refused as a cycle
+func TestCrossingMovesRejectCycle(t *testing.T) {
+	store := newTreeStore()
+	service := NewCategoryService(store)
+
+	require.NoError(t, service.MoveCategory(4, models.CategoryMove{ParentID: parentId(3)}))
+	assert.Equal(t, 3, store.categories[4].CategDepth)
+	assert.ErrorIs(t, service.MoveCategory(3, models.CategoryMove{ParentID: parentId(4)}), ErrCategoryCycle)
+	assert.Equal(t, 2, *store.categories[3].CategParentID)
+}
+
+// Test the depth limit counts the levels below the moved category
+func TestMoveCategoryDepthLimit(t *testing.T) {
+	store := newTreeStore()
+	store.categories[5] = models.Category{CategID: 5, CategName: "Deep", CategParentID: parentId(4), CategDepth: maxCategoryLevels - 2}
+
+	// audio has one level below it, so under depth 8 it would reach depth 10
+	assert.ErrorIs(t, NewCategoryService(store).MoveCategory(2, models.CategoryMove{ParentID: parentId(5)}), ErrCategoryTooDeep)
+	assert.NoError(t, NewCategoryService(store).MoveCategory(3, models.CategoryMove{ParentID: parentId(5)}))
+}
+
+// Test delete options are validated before the repository runs the delete
+func TestDeleteCategoryOptions(t *testing.T) {
+	store := newTreeStore()
+	service := NewCategoryService(store)
+
+	_, err := service.DeleteCategory(4, models.CategoryDelete{ReassignTo: 1, ArchiveProducts: true})
+	assert.ErrorIs(t, err, ErrConflictingDeleteOptions)
+	_, err = service.DeleteCategory(4, models.CategoryDelete{ReassignTo: 4})
+	assert.ErrorIs(t, err, ErrReassignToSelf)
+	_, err = service.DeleteCategory(4, models.CategoryDelete{ReassignTo: 99})
+	assert.ErrorIs(t, err, ErrReassignTargetNotFound)
+	assert.Nil(t, store.deleted)
+
+	moved, err := service.DeleteCategory(4, models.CategoryDelete{ReassignTo: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), moved)
+	assert.Equal(t, 1, store.deleted.ReassignTo)
+}
+
+// Test the in-use error is a 409 telling the client what to do
+func TestCategoryInUseError(t *testing.T) {
+	var domainErr *models.DomainError
+
+	err := errCategoryInUse(0, 3)
+	require.ErrorAs(t, err, &domainErr)
+	assert.Equal(t, models.ErrKindConflict, domainErr.Kind)
+	assert.Contains(t, err.Error(), "reassignTo")
+
+	err = errCategoryInUse(2, 3)
+	assert.Contains(t, err.Error(), "subcategories first")
+}
+
+// Test a subtree with figures is cut from the full tree, keeping the figures of each node
+func TestGetSubtreeWithStats(t *testing.T) {
+	service := NewCategoryService(newTreeStore())
+
+	audio, err := service.GetSubtree(2, true)
+	require.NoError(t, err)
+	assert.Equal(t, 2, audio.Stats.Products)
+	require.Len(t, audio.Children, 1)
+	assert.Equal(t, 3, audio.Children[0].Stats.Products)
+
+	_, err = service.GetSubtree(99, true)
+	assert.ErrorIs(t, err, ErrCategoryNotFound)
+}
+
+// Test the product breadcrumb only resolves products the storefront shows
+func TestGetProductBreadcrumbVisibility(t *testing.T) {
+	service := NewCategoryService(newTreeStore())
+
+	breadcrumb, err := service.GetProductBreadcrumb(10)
+	require.NoError(t, err)
+	require.Len(t, breadcrumb, 3)
+	assert.Equal(t, "electronics", breadcrumb[0].CategPath)
+
+	for _, id := range []int{11, 12, 99} {
+		_, err := service.GetProductBreadcrumb(id)
+		assert.ErrorIs(t, err, ErrProductNotFound)
+	}
+}
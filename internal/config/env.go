@@ -2,12 +2,36 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"time"
 )
 
 type EnvConfig struct {
 	SecretName string
 	UrlPrefix  string
 	DBName     string
+
+	// Connection pool tuning, shared by every warm Lambda invocation
+	DBMaxOpenConns    int
+	DBMaxIdleConns    int
+	DBConnMaxLifetime time.Duration
+	DBConnMaxIdleTime time.Duration
+	// How often a cached pool is pinged before being reused
+	DBHealthCheckInterval time.Duration
+
+	// Token verification: keys come from JWKSFile when set, otherwise JWKSURL
+	// (defaults to the issuer's /.well-known/jwks.json)
+	JWKSURL       string
+	JWKSFile      string
+	TokenIssuer   string
+	TokenClientID string
+	TokenUse      string
+
+	// Product search: "sql" (FULLTEXT index) or "memory" (in-process fuzzy index, rebuilt after SearchIndexTTL)
+	SearchBackend  string
+	SearchIndexTTL time.Duration
+	// How long suggestion data (categories, popular searches, answers) is served from memory
+	SuggestCacheTTL time.Duration
 }
 
 // LoadConfig loads all configuration values from environment variables
@@ -17,5 +41,47 @@ func LoadConfig() (*EnvConfig, error) {
 		SecretName: os.Getenv("SecretName"),
 		UrlPrefix:  os.Getenv("UrlPrefix"),
 		DBName:     "gambit", // Can be replaced with os.Getenv("DB_NAME") if needed
+
+		DBMaxOpenConns:        intEnv("DB_MAX_OPEN_CONNS", 5),
+		DBMaxIdleConns:        intEnv("DB_MAX_IDLE_CONNS", 2),
+		DBConnMaxLifetime:     durationEnv("DB_CONN_MAX_LIFETIME", 5*time.Minute),
+		DBConnMaxIdleTime:     durationEnv("DB_CONN_MAX_IDLE_TIME", time.Minute),
+		DBHealthCheckInterval: durationEnv("DB_HEALTH_CHECK_INTERVAL", 30*time.Second),
+
+		JWKSURL:       os.Getenv("JWKS_URL"),
+		JWKSFile:      os.Getenv("JWKS_FILE"),
+		TokenIssuer:   os.Getenv("TOKEN_ISSUER"),
+		TokenClientID: os.Getenv("TOKEN_CLIENT_ID"),
+		TokenUse:      stringEnv("TOKEN_USE", "access"),
+
+		SearchBackend:   stringEnv("SEARCH_BACKEND", "sql"),
+		SearchIndexTTL:  durationEnv("SEARCH_INDEX_TTL", 5*time.Minute),
+		SuggestCacheTTL: durationEnv("SUGGEST_CACHE_TTL", time.Minute),
 	}, nil
 }
+
+// stringEnv reads a variable, falling back when missing
+func stringEnv(key, fallback string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return fallback
+}
+
+// intEnv reads an integer variable, falling back when missing or invalid
+func intEnv(key string, fallback int) int {
+	val, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return val
+}
+
+// durationEnv reads a duration like "90s" or "5m", falling back when missing or invalid
+func durationEnv(key string, fallback time.Duration) time.Duration {
+	val, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return val
+}
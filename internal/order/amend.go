@@ -0,0 +1,107 @@
+package order
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/ddessilvestri/ecommerce-go/models"
+)
+
+// editableStatuses are the statuses an order can be amended in: nothing has been charged or prepared yet
+var editableStatuses = map[string]bool{
+	models.OrderStatusPending: true,
+}
+
+// Amend changes the lines of one of the user's orders to the ones in o. The lines are diffed against the
+// stored ones, only the net quantity change is taken from (or given back to) stock, the whole order is
+// repriced at current prices and the amendment is recorded. o.AddId, when set, also moves the order
+// to that address, which must be one of the user's.
+func (s *Service) Amend(id int, userUUID string, o models.Orders) (models.OrderAmendment, error) {
+	if err := validateLines(o); err != nil {
+		return models.OrderAmendment{}, err
+	}
+
+	current, err := s.GetByIdWithUserValidation(id, userUUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.OrderAmendment{}, ErrOrderNotFound
+	}
+	if err != nil {
+		return models.OrderAmendment{}, err
+	}
+	if !editableStatuses[current.Status] {
+		return models.OrderAmendment{}, errNotEditable(current.Status)
+	}
+	if o.AddId == current.AddId {
+		o.AddId = 0
+	}
+	if len(diffLines(current.OrderDetails, o.OrderDetails)) == 0 && o.AddId == 0 {
+		return models.OrderAmendment{}, ErrNothingToAmend
+	}
+
+	o.Id = id
+	o.UserUUID = userUUID
+	return s.repo.Amend(o, current.Status, pricePlan(o))
+}
+
+// Amendments returns the amendment history of one of the user's orders, oldest first
+func (s *Service) Amendments(id int, userUUID string) ([]models.OrderAmendment, error) {
+	if _, err := s.GetByIdWithUserValidation(id, userUUID); err != nil {
+		return nil, err
+	}
+	amendments, err := s.repo.GetAmendments(id)
+	if err != nil {
+		return nil, err
+	}
+	if amendments == nil {
+		amendments = []models.OrderAmendment{}
+	}
+	return amendments, nil
+}
+
+// diffLines compares the quantity of each product before and after, over all the lines of the product.
+// Products whose quantity didn't change are left out; the result is sorted by product id.
+func diffLines(before, after []models.OrdersDetails) []models.OrderLineChange {
+	from, to := stockNeeded(before), stockNeeded(after)
+	all := map[int]int{}
+	for id := range from {
+		all[id] = 0
+	}
+	for id := range to {
+		all[id] = 0
+	}
+
+	var changes []models.OrderLineChange
+	for _, id := range sortedIds(all) {
+		if from[id] != to[id] {
+			changes = append(changes, models.OrderLineChange{ProdId: id, From: from[id], To: to[id]})
+		}
+	}
+	return changes
+}
+
+// stockDelta is the stock an amendment takes from each product; negative quantities go back to stock
+func stockDelta(changes []models.OrderLineChange) map[int]int {
+	delta := map[int]int{}
+	for _, c := range changes {
+		delta[c.ProdId] = c.To - c.From
+	}
+	return delta
+}
+
+// extraStock keeps the products an amendment takes more of, the only ones that need stock checked
+func extraStock(delta map[int]int) map[int]int {
+	extra := map[int]int{}
+	for id, units := range delta {
+		if units > 0 {
+			extra[id] = units
+		}
+	}
+	return extra
+}
+
+func errNotEditable(status string) error {
+	return models.NewConflictError(fmt.Sprintf("order is %s: only pending orders can be amended", status))
+}
+
+var ErrNothingToAmend = models.NewValidationError("nothing to amend: the lines and address are unchanged")
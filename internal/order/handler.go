@@ -26,79 +26,137 @@ func (h *Handler) Post(requestWithContext models.RequestWithContext) *events.API
 
 	err := json.Unmarshal([]byte(body), &o)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return tools.ErrorResponse(requestWithContext, models.NewValidationError("invalid JSON body").Wrap(err))
 	}
 
 	userUUID, err := authContext.UserUUIDFromContext(requestWithContext.Context())
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusUnauthorized, "User not found in context: "+err.Error())
+		return tools.ErrorResponse(requestWithContext, err)
 	}
 
 	o.UserUUID = userUUID
 
 	id, err := h.service.Create(o)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusInternalServerError, "Error creating order: "+err.Error())
+		return tools.ErrorResponse(requestWithContext, err)
 	}
 
 	return tools.CreateAPIResponse(http.StatusOK, fmt.Sprintf(`{"OrderId": %d}`, id))
 }
 
+// Quote handles POST /order/quote: the order priced at current catalog prices, nothing is stored
+func (h *Handler) Quote(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	var o models.Orders
+	body := requestWithContext.RequestBody()
+
+	err := json.Unmarshal([]byte(body), &o)
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, models.NewValidationError("invalid JSON body").Wrap(err))
+	}
+
+	quoted, err := h.service.Quote(o)
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, err)
+	}
+
+	response, err := json.Marshal(quoted)
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, err)
+	}
+	return tools.CreateAPIResponse(http.StatusOK, string(response))
+}
+
+// Put handles PUT /order/{id}: the same amendment as POST /order/{id}/amend
 func (h *Handler) Put(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	return h.Amend(requestWithContext)
+}
+
+// Amend handles POST /order/{id}/amend with the lines the order should have from now on,
+// and answers with what changed
+func (h *Handler) Amend(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
 	idStr := requestWithContext.RequestPathParameters()["id"]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "Invalid OrderId: "+err.Error())
+		return tools.ErrorResponse(requestWithContext, models.NewFieldError("id", "invalid order Id").Wrap(err))
 	}
 
 	var o models.Orders
-	body := requestWithContext.RequestBody()
-
-	err = json.Unmarshal([]byte(body), &o)
+	err = json.Unmarshal([]byte(requestWithContext.RequestBody()), &o)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return tools.ErrorResponse(requestWithContext, models.NewValidationError("invalid JSON body").Wrap(err))
 	}
 
 	userUUID, err := authContext.UserUUIDFromContext(requestWithContext.Context())
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusUnauthorized, "User not found in context: "+err.Error())
+		return tools.ErrorResponse(requestWithContext, err)
 	}
 
-	o.Id = id
-	o.UserUUID = userUUID
+	amendment, err := h.service.Amend(id, userUUID, o)
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, err)
+	}
+	return jsonResponse(requestWithContext, amendment)
+}
+
+// Amendments handles GET /order/{id}/amendments: the amendment history of one of the customer's orders
+func (h *Handler) Amendments(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	idStr := requestWithContext.RequestPathParameters()["id"]
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, models.NewFieldError("id", "invalid order Id").Wrap(err))
+	}
 
-	err = h.service.Update(o)
+	userUUID, err := authContext.UserUUIDFromContext(requestWithContext.Context())
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusInternalServerError, "Error updating order: "+err.Error())
+		return tools.ErrorResponse(requestWithContext, err)
 	}
 
-	return tools.CreateAPIResponse(http.StatusOK, fmt.Sprintf(`{"UpdatedOrderId": %d}`, id))
+	amendments, err := h.service.Amendments(id, userUUID)
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, err)
+	}
+	return jsonResponse(requestWithContext, amendments)
 }
 
+// Delete handles DELETE /order/{id}?reason=: the order is cancelled, not removed
 func (h *Handler) Delete(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	return h.cancel(requestWithContext, requestWithContext.RequestQueryStringParameters()["reason"])
+}
+
+// Cancel handles POST /order/{id}/cancel with a body like {"reason": "ordered the wrong size"}
+func (h *Handler) Cancel(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal([]byte(requestWithContext.RequestBody()), &body); err != nil {
+		return tools.ErrorResponse(requestWithContext, models.NewValidationError("invalid JSON body").Wrap(err))
+	}
+	return h.cancel(requestWithContext, body.Reason)
+}
+
+func (h *Handler) cancel(requestWithContext models.RequestWithContext, reason string) *events.APIGatewayProxyResponse {
 	idStr := requestWithContext.RequestPathParameters()["id"]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "Invalid OrderId: "+err.Error())
+		return tools.ErrorResponse(requestWithContext, models.NewFieldError("id", "invalid order Id").Wrap(err))
 	}
 
 	userUUID, err := authContext.UserUUIDFromContext(requestWithContext.Context())
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusUnauthorized, "User not found in context: "+err.Error())
+		return tools.ErrorResponse(requestWithContext, err)
 	}
 
-	err = h.service.Delete(id, userUUID)
+	timeline, err := h.service.Cancel(id, userUUID, reason)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusInternalServerError, "Error deleting order: "+err.Error())
+		return tools.ErrorResponse(requestWithContext, err)
 	}
-
-	return tools.CreateAPIResponse(http.StatusOK, fmt.Sprintf(`{"DeletedOrderId": %d}`, id))
+	return jsonResponse(requestWithContext, timeline)
 }
 
 func (h *Handler) Get(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
 	userUUID, err := authContext.UserUUIDFromContext(requestWithContext.Context())
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusUnauthorized, "User not found in context: "+err.Error())
+		return tools.ErrorResponse(requestWithContext, err)
 	}
 
 	// === 1. Get Order By Id ===
@@ -106,17 +164,17 @@ func (h *Handler) Get(requestWithContext models.RequestWithContext) *events.APIG
 	if orderIdStr != "" {
 		id, err := strconv.Atoi(orderIdStr)
 		if err != nil {
-			return tools.CreateAPIResponse(http.StatusBadRequest, "Invalid OrderId: "+err.Error())
+			return tools.ErrorResponse(requestWithContext, models.NewFieldError("id", "invalid order Id").Wrap(err))
 		}
 
 		order, err := h.service.GetByIdWithUserValidation(id, userUUID)
 		if err != nil {
-			return tools.CreateAPIResponse(http.StatusNotFound, "Order not found: "+err.Error())
+			return tools.ErrorResponse(requestWithContext, err)
 		}
 
 		body, err := json.Marshal(order)
 		if err != nil {
-			return tools.CreateAPIResponse(http.StatusInternalServerError, "Error converting to JSON: "+err.Error())
+			return tools.ErrorResponse(requestWithContext, err)
 		}
 
 		return tools.CreateAPIResponse(http.StatusOK, string(body))
@@ -124,22 +182,92 @@ func (h *Handler) Get(requestWithContext models.RequestWithContext) *events.APIG
 
 	// === 2. Default: Get all orders paginated ===
 
-	query := requestWithContext.RequestQueryStringParameters()
-	page, fromDate, toDate, err := tools.ParseOrdersPaginationAndSorting(query)
+	query := map[string]string{}
+	for key, value := range requestWithContext.RequestQueryStringParameters() {
+		if alias, ok := legacyDateParameters[key]; ok {
+			key = alias
+		}
+		query[key] = value
+	}
+
+	q, err := QuerySpec.Parse(query)
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, err)
+	}
+
+	orders, err := h.service.GetAllByUserUUID(userUUID, q)
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, err)
+	}
+
+	return tools.ListResponse(requestWithContext, orders, q)
+}
+
+// Status handles POST /order/{id}/status (admin): moves the order along its lifecycle
+// and answers with the updated timeline
+func (h *Handler) Status(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	idStr := requestWithContext.RequestPathParameters()["id"]
+	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, err.Error())
+		return tools.ErrorResponse(requestWithContext, models.NewFieldError("id", "invalid order Id").Wrap(err))
+	}
+
+	var update models.OrderStatusUpdate
+	if err := json.Unmarshal([]byte(requestWithContext.RequestBody()), &update); err != nil {
+		return tools.ErrorResponse(requestWithContext, models.NewValidationError("invalid JSON body").Wrap(err))
 	}
 
-	limit := 10 // Default limit
+	userUUID, err := authContext.UserUUIDFromContext(requestWithContext.Context())
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, err)
+	}
 
-	orders, err := h.service.GetAllByUserUUID(page, limit, fromDate, toDate, userUUID)
+	timeline, err := h.service.ChangeStatus(id, update, userUUID, models.RoleAdmin)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusInternalServerError, err.Error())
+		return tools.ErrorResponse(requestWithContext, err)
 	}
-	body, err := json.Marshal(orders)
+	return jsonResponse(requestWithContext, timeline)
+}
+
+// Timeline handles GET /order/{id}/timeline: the status history of one of the customer's orders
+func (h *Handler) Timeline(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	idStr := requestWithContext.RequestPathParameters()["id"]
+	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusInternalServerError, "Error converting to JSON: "+err.Error())
+		return tools.ErrorResponse(requestWithContext, models.NewFieldError("id", "invalid order Id").Wrap(err))
 	}
 
+	userUUID, err := authContext.UserUUIDFromContext(requestWithContext.Context())
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, err)
+	}
+
+	timeline, err := h.service.Timeline(id, userUUID)
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, err)
+	}
+	return jsonResponse(requestWithContext, timeline)
+}
+
+// AdminTimeline handles GET /admin/orders/{id}/timeline (admin): the status history of any order
+func (h *Handler) AdminTimeline(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	idStr := requestWithContext.RequestPathParameters()["id"]
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, models.NewFieldError("id", "invalid order Id").Wrap(err))
+	}
+
+	timeline, err := h.service.AdminTimeline(id)
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, err)
+	}
+	return jsonResponse(requestWithContext, timeline)
+}
+
+func jsonResponse(requestWithContext models.RequestWithContext, v interface{}) *events.APIGatewayProxyResponse {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, err)
+	}
 	return tools.CreateAPIResponse(http.StatusOK, string(body))
 }
@@ -1,11 +1,18 @@
 package order
 
-import "github.com/ddessilvestri/ecommerce-go/models"
+import (
+	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/ddessilvestri/ecommerce-go/tools"
+)
 
 type Storage interface {
-	Insert(o models.Orders) (int64, error)
+	Insert(o models.Orders, price PricePlan) (int64, error)
 	GetById(id int) (models.Orders, error)
-	GetAllByUserUUID(page int, limit int, fromDate string, toDate string, userUUID string) ([]models.Orders, error)
-	Update(o models.Orders) error
-	Delete(id int, userUUID string) error
+	GetAllByUserUUID(userUUID string, q tools.Query) ([]models.Orders, error)
+	CountByUserUUID(userUUID string, q tools.Query) (int, error)
+	Amend(o models.Orders, status string, price PricePlan) (models.OrderAmendment, error)
+	GetAmendments(id int) ([]models.OrderAmendment, error)
+	GetProductPrices(ids []int) (map[int]models.Product, error)
+	ChangeStatus(id int, change models.OrderStatusChange, restock bool) error
+	GetStatusHistory(id int) ([]models.OrderStatusChange, error)
 }
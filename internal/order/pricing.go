@@ -0,0 +1,101 @@
+package order
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ddessilvestri/ecommerce-go/models"
+)
+
+// PricePlan prices an order from the product rows the repository read with their lock,
+// so what is charged is read in the same transaction that takes the stock
+type PricePlan func(catalog map[int]models.Product) (models.Orders, error)
+
+// quote prices o at the current catalog prices, for a preview: nothing is locked
+func (s *Service) quote(o models.Orders) (models.Orders, error) {
+	ids := make([]int, 0, len(o.OrderDetails))
+	for _, d := range o.OrderDetails {
+		ids = append(ids, d.ProdId)
+	}
+	catalog, err := s.repo.GetProductPrices(ids)
+	if err != nil {
+		return models.Orders{}, err
+	}
+	return priceOrder(o, catalog)
+}
+
+// pricePlan prices o from the locked rows and refuses it when the amounts the client sent disagree
+func pricePlan(o models.Orders) PricePlan {
+	return func(catalog map[int]models.Product) (models.Orders, error) {
+		quoted, err := priceOrder(o, catalog)
+		if err != nil {
+			return models.Orders{}, err
+		}
+		if err := checkQuote(o, quoted); err != nil {
+			return models.Orders{}, err
+		}
+		return quoted, nil
+	}
+}
+
+// priceOrder prices every line from the catalog and totals the order.
+// Amounts are summed in cents so the total is exactly the sum of the lines.
+func priceOrder(o models.Orders, catalog map[int]models.Product) (models.Orders, error) {
+	quoted := o
+	quoted.OrderDetails = make([]models.OrdersDetails, len(o.OrderDetails))
+	var total int64
+	for i, d := range o.OrderDetails {
+		field := fmt.Sprintf("OrderDetails[%d].prodId", i)
+		p, ok := catalog[d.ProdId]
+		if !ok {
+			return models.Orders{}, models.NewFieldError(field, fmt.Sprintf("product %d not found", d.ProdId))
+		}
+		if p.Status != models.ProductActive || p.Price <= 0 {
+			return models.Orders{}, models.NewFieldError(field, fmt.Sprintf("product %d is not for sale", d.ProdId))
+		}
+
+		line := toCents(p.Price) * int64(d.Quantity)
+		d.Price = p.Price
+		d.LineTotal = fromCents(line)
+		quoted.OrderDetails[i] = d
+		total += line
+	}
+	quoted.Total = fromCents(total)
+	return quoted, nil
+}
+
+// checkQuote refuses an order whose client-sent prices or total disagree with the quote.
+// Amounts left out (zero) are not checked. The conflict lists the current amounts to resubmit with.
+func checkQuote(sent, quoted models.Orders) error {
+	var fields []models.FieldError
+	for i, d := range sent.OrderDetails {
+		current := quoted.OrderDetails[i].Price
+		if d.Price != 0 && toCents(d.Price) != toCents(current) {
+			fields = append(fields, models.FieldError{
+				Field:   fmt.Sprintf("OrderDetails[%d].price", i),
+				Message: fmt.Sprintf("current price is %.2f", current),
+			})
+		}
+	}
+	if sent.Total != 0 && toCents(sent.Total) != toCents(quoted.Total) {
+		fields = append(fields, models.FieldError{
+			Field:   "orderTotal",
+			Message: fmt.Sprintf("current total is %.2f", quoted.Total),
+		})
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	err := *ErrPriceChanged
+	err.Fields = fields
+	return &err
+}
+
+func toCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+func fromCents(cents int64) float64 {
+	return float64(cents) / 100
+}
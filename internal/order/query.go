@@ -0,0 +1,43 @@
+package order
+
+import (
+	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/ddessilvestri/ecommerce-go/tools"
+)
+
+// dateColumn is the nullable Order_Date, coalesced in the sort and in selects so a cursor holds the exact sort value
+const dateColumn = "COALESCE(Order_Date, TIMESTAMP '1000-01-01 00:00:00')"
+
+// QuerySpec declares the order fields the order history can sort, filter and select on
+var QuerySpec = tools.QuerySpec{
+	Fields: map[string]tools.Field{
+		"id":         {Column: "Order_Id", JSON: "orderId", Type: tools.IntField, Sortable: true, Filterable: true},
+		"date":       {Column: dateColumn, JSON: "orderDate", Type: tools.DateField, Sortable: true, Filterable: true},
+		"total":      {Column: "Order_Total", JSON: "orderTotal", Type: tools.NumberField, Sortable: true, Filterable: true},
+		"address_id": {Column: "Order_AddId", JSON: "orderAddId", Type: tools.IntField, Filterable: true},
+		"status":     {Column: "Order_Status", JSON: "orderStatus", Filterable: true},
+		"details":    {JSON: "OrderDetails"},
+	},
+	IdColumn:     "Order_Id",
+	DefaultSort:  "id",
+	DefaultOrder: "DESC",
+	Cursor:       true,
+}
+
+// legacyDateParameters keeps ?from_date= and ?to_date= working as date filters
+var legacyDateParameters = map[string]string{
+	"from_date": "date_gte",
+	"to_date":   "date_lte",
+}
+
+// sortValue is the value of the field o is sorted on, as stored in a cursor
+func sortValue(o models.Orders, sortBy string) interface{} {
+	switch sortBy {
+	case "date":
+		return o.Date
+	case "total":
+		return o.Total
+	default:
+		return o.Id
+	}
+}
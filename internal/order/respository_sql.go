@@ -2,6 +2,11 @@ package order
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/ddessilvestri/ecommerce-go/tools"
 
 	"github.com/ddessilvestri/ecommerce-go/models"
 )
@@ -19,11 +24,42 @@ func NewSQLRepository(db *sql.DB) Storage {
 	return &repositorySQL{db: db}
 }
 
-func (r *repositorySQL) Insert(o models.Orders) (int64, error) {
+// Insert stores the order and takes its lines out of stock in one transaction.
+// The product rows stay locked until the commit, so two orders can't both take the last unit,
+// and the order is priced by price from those same rows, so it can't be charged a price that just changed.
+func (r *repositorySQL) Insert(o models.Orders, price PricePlan) (int64, error) {
 	tx, err := r.db.Begin()
 	if err != nil {
 		return 0, err
 	}
+	defer tx.Rollback()
+
+	if err := lockAddress(tx, o.AddId, o.UserUUID); err != nil {
+		return 0, err
+	}
+	needed := stockNeeded(o.OrderDetails)
+	catalog, err := lockProducts(tx, sortedIds(needed))
+	if err != nil {
+		return 0, err
+	}
+	o, err = price(catalog)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkStock(o.OrderDetails, needed, stockOf(catalog)); err != nil {
+		return 0, err
+	}
+	for _, id := range sortedIds(needed) {
+		_, err := tx.Exec(`
+			UPDATE products
+			SET Prod_Stock = Prod_Stock - ?, Prod_Updated = NOW()
+			WHERE Prod_Id = ?`,
+			needed[id], id,
+		)
+		if err != nil {
+			return 0, err
+		}
+	}
 
 	res, err := tx.Exec(`
 		INSERT INTO orders (Order_UserUUID, Order_AddId, Order_Date, Order_Total)
@@ -31,13 +67,19 @@ func (r *repositorySQL) Insert(o models.Orders) (int64, error) {
 		o.UserUUID, o.AddId, o.Total,
 	)
 	if err != nil {
-		tx.Rollback()
 		return 0, err
 	}
 
 	orderID, err := res.LastInsertId()
 	if err != nil {
-		tx.Rollback()
+		return 0, err
+	}
+
+	if err := insertStatusChange(tx, orderID, models.OrderStatusChange{
+		To:        models.OrderStatusPending,
+		Actor:     o.UserUUID,
+		ActorRole: string(models.RoleCustomer),
+	}); err != nil {
 		return 0, err
 	}
 
@@ -48,7 +90,6 @@ func (r *repositorySQL) Insert(o models.Orders) (int64, error) {
 			orderID, d.ProdId, d.Quantity, d.Price,
 		)
 		if err != nil {
-			tx.Rollback()
 			return 0, err
 		}
 	}
@@ -61,48 +102,88 @@ func (r *repositorySQL) Insert(o models.Orders) (int64, error) {
 	return int64(orderID), nil
 }
 
+// lockAddress checks the address belongs to the user, locking it so it can't be deleted before the commit
+func lockAddress(tx *sql.Tx, addId int, userUUID string) error {
+	var exists int
+	err := tx.QueryRow(`
+		SELECT 1
+		FROM addresses
+		WHERE Add_Id = ? AND Add_UserID = ?
+		FOR UPDATE`,
+		addId, userUUID,
+	).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrAddressNotFound
+	}
+	return err
+}
+
+// lockProducts reads the price, status and stock of the products with their rows locked FOR UPDATE, keyed by id.
+// ids must be sorted; products with no price are left at 0 and products that don't exist are missing from the map.
+func lockProducts(tx *sql.Tx, ids []int) (map[int]models.Product, error) {
+	query, args, err := squirrel.
+		Select("Prod_Id", "Prod_Price", "Prod_Status", "COALESCE(Prod_Stock, 0)").
+		From("products").
+		Where(squirrel.Eq{"Prod_Id": ids}).
+		OrderBy("Prod_Id").
+		Suffix("FOR UPDATE").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := map[int]models.Product{}
+	for rows.Next() {
+		var p models.Product
+		var price sql.NullFloat64
+		if err := rows.Scan(&p.Id, &price, &p.Status, &p.Stock); err != nil {
+			return nil, err
+		}
+		p.Price = price.Float64
+		products[p.Id] = p
+	}
+	return products, rows.Err()
+}
+
 func (r *repositorySQL) GetById(id int) (models.Orders, error) {
 	var o models.Orders
 	err := r.db.QueryRow(`
-		SELECT Order_Id, Order_UserUUID, Order_AddId, Order_Date, Order_Total
+		SELECT Order_Id, Order_UserUUID, Order_AddId, `+dateColumn+`, Order_Total, Order_Status
 		FROM orders
 		WHERE Order_Id = ?`,
 		id,
-	).Scan(&o.Id, &o.UserUUID, &o.AddId, &o.Date, &o.Total)
+	).Scan(&o.Id, &o.UserUUID, &o.AddId, &o.Date, &o.Total, &o.Status)
 	if err != nil {
 		return models.Orders{}, err
 	}
 
-	rows, err := r.db.Query(`
-		SELECT OD_Id, OD_OrderId, OD_ProdId, OD_Quantity, OD_Price
-		FROM orders_detail
-		WHERE OD_OrderId = ?`,
-		id,
-	)
+	o.OrderDetails, err = r.getDetails(id)
 	if err != nil {
 		return models.Orders{}, err
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var d models.OrdersDetails
-		if err := rows.Scan(&d.Id, &d.OrderId, &d.ProdId, &d.Quantity, &d.Price); err != nil {
-			return models.Orders{}, err
-		}
-		o.OrderDetails = append(o.OrderDetails, d)
-	}
 
 	return o, nil
 }
 
-func (r *repositorySQL) GetAllByUserUUID(page, limit int, fromDate, toDate string, userUUID string) ([]models.Orders, error) {
-	rows, err := r.db.Query(`
-		SELECT Order_Id, Order_UserUUID, Order_AddId, Order_Date, Order_Total
-		FROM orders
-		WHERE Order_UserUUID = ?
-		ORDER BY Order_Id DESC`,
-		userUUID,
-	)
+// GetAllByUserUUID returns the page of the user's orders described by q
+func (r *repositorySQL) GetAllByUserUUID(userUUID string, q tools.Query) ([]models.Orders, error) {
+	query, args, err := q.ApplyTo(squirrel.
+		Select("Order_Id", "Order_UserUUID", "Order_AddId", dateColumn, "Order_Total", "Order_Status").
+		From("orders").
+		Where(squirrel.Eq{"Order_UserUUID": userUUID}).
+		PlaceholderFormat(squirrel.Question)).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -112,103 +193,390 @@ func (r *repositorySQL) GetAllByUserUUID(page, limit int, fromDate, toDate strin
 
 	for rows.Next() {
 		var o models.Orders
-		err := rows.Scan(&o.Id, &o.UserUUID, &o.AddId, &o.Date, &o.Total)
+		err := rows.Scan(&o.Id, &o.UserUUID, &o.AddId, &o.Date, &o.Total, &o.Status)
 		if err != nil {
 			return nil, err
 		}
+		orders = append(orders, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-		detailsRows, err := r.db.Query(`
-					SELECT OD_Id, OD_OrderId, OD_ProdId, OD_Quantity, OD_Price
-		FROM orders_detail
-		WHERE OD_OrderId = ?`,
-			o.Id,
-		)
+	for i := range orders {
+		details, err := r.getDetails(orders[i].Id)
 		if err != nil {
 			return nil, err
 		}
-		defer detailsRows.Close()
+		orders[i].OrderDetails = details
+	}
+
+	return orders, nil
+}
+
+func (r *repositorySQL) CountByUserUUID(userUUID string, q tools.Query) (int, error) {
+	query, args, err := q.ApplyFiltersTo(squirrel.
+		Select("COUNT(*)").
+		From("orders").
+		Where(squirrel.Eq{"Order_UserUUID": userUUID}).
+		PlaceholderFormat(squirrel.Question)).
+		ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	var total int
+	err = r.db.QueryRow(query, args...).Scan(&total)
+	return total, err
+}
+
+func (r *repositorySQL) getDetails(orderId int) ([]models.OrdersDetails, error) {
+	rows, err := r.db.Query(`
+		SELECT OD_Id, OD_OrderId, OD_ProdId, COALESCE(Prod_Title, ''), OD_Quantity, OD_Price
+		FROM orders_detail
+		LEFT JOIN products ON Prod_Id = OD_ProdId
+		WHERE OD_OrderId = ?`,
+		orderId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-		for detailsRows.Next() {
-			var d models.OrdersDetails
-			if err := detailsRows.Scan(&d.Id, &d.OrderId, &d.ProdId, &d.Quantity, &d.Price); err != nil {
-				return nil, err
-			}
-			o.OrderDetails = append(o.OrderDetails, d)
+	var details []models.OrdersDetails
+	for rows.Next() {
+		var d models.OrdersDetails
+		if err := rows.Scan(&d.Id, &d.OrderId, &d.ProdId, &d.ProdTitle, &d.Quantity, &d.Price); err != nil {
+			return nil, err
 		}
+		d.LineTotal = fromCents(toCents(d.Price) * int64(d.Quantity))
+		details = append(details, d)
+	}
+	return details, rows.Err()
+}
 
-		orders = append(orders, o)
+// GetProductPrices returns the price and status of the given products, keyed by id.
+// Products with no price are left at 0; ids that don't exist are missing from the map.
+func (r *repositorySQL) GetProductPrices(ids []int) (map[int]models.Product, error) {
+	query, args, err := squirrel.
+		Select("Prod_Id", "Prod_Price", "Prod_Status").
+		From("products").
+		Where(squirrel.Eq{"Prod_Id": ids}).
+		ToSql()
+	if err != nil {
+		return nil, err
 	}
 
-	return orders, nil
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := map[int]models.Product{}
+	for rows.Next() {
+		var p models.Product
+		var price sql.NullFloat64
+		if err := rows.Scan(&p.Id, &price, &p.Status); err != nil {
+			return nil, err
+		}
+		p.Price = price.Float64
+		products[p.Id] = p
+	}
+	return products, rows.Err()
 }
 
-func (r *repositorySQL) Update(o models.Orders) error {
+// ChangeStatus stores a new status and its timeline entry in one transaction.
+// The update only applies while the order is still in change.From, so concurrent changes can't both win.
+// With restock, the quantities of the order go back to Prod_Stock in the same transaction.
+func (r *repositorySQL) ChangeStatus(id int, change models.OrderStatusChange, restock bool) error {
 	tx, err := r.db.Begin()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	_, err = tx.Exec(`
+	res, err := tx.Exec(`
 		UPDATE orders
-		SET Order_AddId = ?, Order_Total = ?
-		WHERE Order_Id = ? AND Order_UserUUID = ?`,
-		o.AddId, o.Total, o.Id, o.UserUUID,
+		SET Order_Status = ?
+		WHERE Order_Id = ? AND Order_Status = ?`,
+		change.To, id, change.From,
 	)
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrOrderStatusChanged
+	}
 
-	_, err = tx.Exec(`
-		DELETE FROM orders_detail
-		WHERE OD_OrderId = ?`,
-		o.Id,
+	if restock {
+		if err := restockOrder(tx, id); err != nil {
+			return err
+		}
+	}
+
+	if err := insertStatusChange(tx, int64(id), change); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetStatusHistory returns the timeline of an order, oldest change first
+func (r *repositorySQL) GetStatusHistory(id int) ([]models.OrderStatusChange, error) {
+	rows, err := r.db.Query(`
+		SELECT COALESCE(OSH_From, ''), OSH_To, COALESCE(OSH_Actor, ''), OSH_ActorRole, COALESCE(OSH_Note, ''), OSH_Date
+		FROM order_status_history
+		WHERE OSH_OrderId = ?
+		ORDER BY OSH_Date, OSH_Id`,
+		id,
 	)
 	if err != nil {
-		tx.Rollback()
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []models.OrderStatusChange
+	for rows.Next() {
+		var c models.OrderStatusChange
+		if err := rows.Scan(&c.From, &c.To, &c.Actor, &c.ActorRole, &c.Note, &c.Date); err != nil {
+			return nil, err
+		}
+		history = append(history, c)
+	}
+	return history, rows.Err()
+}
+
+// restockOrder returns the quantities of an order to stock, locking the products in the same order as Insert.
+// Orders that never reserved stock (placed before reservation existed) give nothing back.
+func restockOrder(tx *sql.Tx, orderId int) error {
+	var reserved bool
+	err := tx.QueryRow("SELECT Order_StockReserved FROM orders WHERE Order_Id = ?", orderId).Scan(&reserved)
+	if err != nil {
 		return err
 	}
+	if !reserved {
+		return nil
+	}
 
-	for _, d := range o.OrderDetails {
+	details, err := lockedDetails(tx, orderId)
+	if err != nil {
+		return err
+	}
+	if len(details) == 0 {
+		return nil
+	}
+
+	returned := stockNeeded(details)
+	if _, err := lockProducts(tx, sortedIds(returned)); err != nil {
+		return err
+	}
+	for _, id := range sortedIds(returned) {
 		_, err := tx.Exec(`
-					INSERT INTO orders_detail (OD_OrderId, OD_ProdId, OD_Quantity, OD_Price)
-		VALUES (?, ?, ?, ?)`,
-			o.Id, d.ProdId, d.Quantity, d.Price,
+			UPDATE products
+			SET Prod_Stock = Prod_Stock + ?, Prod_Updated = NOW()
+			WHERE Prod_Id = ?`,
+			returned[id], id,
 		)
 		if err != nil {
-			tx.Rollback()
 			return err
 		}
 	}
+	return nil
+}
 
-	return tx.Commit()
+// lockedDetails reads the product and quantity of each line of an order within tx
+func lockedDetails(tx *sql.Tx, orderId int) ([]models.OrdersDetails, error) {
+	rows, err := tx.Query(`
+		SELECT OD_ProdId, OD_Quantity
+		FROM orders_detail
+		WHERE OD_OrderId = ?
+		FOR UPDATE`,
+		orderId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var details []models.OrdersDetails
+	for rows.Next() {
+		var d models.OrdersDetails
+		if err := rows.Scan(&d.ProdId, &d.Quantity); err != nil {
+			return nil, err
+		}
+		details = append(details, d)
+	}
+	return details, rows.Err()
 }
 
-func (r *repositorySQL) Delete(id int, userUUID string) error {
+// insertStatusChange appends an entry to the timeline of an order; empty From, Actor and Note are stored as NULL
+func insertStatusChange(tx *sql.Tx, orderId int64, change models.OrderStatusChange) error {
+	_, err := tx.Exec(`
+		INSERT INTO order_status_history (OSH_OrderId, OSH_From, OSH_To, OSH_Actor, OSH_ActorRole, OSH_Note, OSH_Date)
+		VALUES (?, NULLIF(?, ''), ?, NULLIF(?, ''), ?, NULLIF(?, ''), NOW())`,
+		orderId, change.From, change.To, change.Actor, change.ActorRole, change.Note,
+	)
+	return err
+}
+
+// Amend replaces the lines of an order with the ones in o, priced by price, and records the amendment, in one transaction.
+// The order row is locked and must still be in status; the diff is taken against the lines stored at that point,
+// and only the net quantity of each product moves in or out of stock. The rows of the products before and after
+// are locked together, and the new lines are priced from them. An order that never reserved stock takes
+// its new lines from stock in full, and is reserved from then on.
+func (r *repositorySQL) Amend(o models.Orders, status string, price PricePlan) (models.OrderAmendment, error) {
 	tx, err := r.db.Begin()
 	if err != nil {
-		return err
+		return models.OrderAmendment{}, err
+	}
+	defer tx.Rollback()
+
+	var current string
+	var totalBefore float64
+	var reserved bool
+	err = tx.QueryRow(`
+		SELECT Order_Status, Order_Total, Order_StockReserved
+		FROM orders
+		WHERE Order_Id = ? AND Order_UserUUID = ?
+		FOR UPDATE`,
+		o.Id, o.UserUUID,
+	).Scan(&current, &totalBefore, &reserved)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.OrderAmendment{}, ErrOrderNotFound
+	}
+	if err != nil {
+		return models.OrderAmendment{}, err
+	}
+	if current != status {
+		return models.OrderAmendment{}, ErrOrderStatusChanged
+	}
+	if o.AddId != 0 {
+		if err := lockAddress(tx, o.AddId, o.UserUUID); err != nil {
+			return models.OrderAmendment{}, err
+		}
+	}
+
+	before, err := lockedDetails(tx, o.Id)
+	if err != nil {
+		return models.OrderAmendment{}, err
+	}
+	changes := diffLines(before, o.OrderDetails)
+	delta := stockDelta(changes)
+	if !reserved {
+		delta = stockDelta(diffLines(nil, o.OrderDetails))
+	}
+
+	locked := stockNeeded(o.OrderDetails)
+	for id := range delta {
+		locked[id] = 0
+	}
+	catalog, err := lockProducts(tx, sortedIds(locked))
+	if err != nil {
+		return models.OrderAmendment{}, err
+	}
+	o, err = price(catalog)
+	if err != nil {
+		return models.OrderAmendment{}, err
+	}
+	if err := checkStock(o.OrderDetails, extraStock(delta), stockOf(catalog)); err != nil {
+		return models.OrderAmendment{}, err
+	}
+	for _, id := range sortedIds(delta) {
+		_, err := tx.Exec(`
+			UPDATE products
+			SET Prod_Stock = Prod_Stock - ?, Prod_Updated = NOW()
+			WHERE Prod_Id = ?`,
+			delta[id], id,
+		)
+		if err != nil {
+			return models.OrderAmendment{}, err
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM orders_detail WHERE OD_OrderId = ?", o.Id); err != nil {
+		return models.OrderAmendment{}, err
+	}
+	for _, d := range o.OrderDetails {
+		_, err := tx.Exec(`
+			INSERT INTO orders_detail (OD_OrderId, OD_ProdId, OD_Quantity, OD_Price)
+			VALUES (?, ?, ?, ?)`,
+			o.Id, d.ProdId, d.Quantity, d.Price,
+		)
+		if err != nil {
+			return models.OrderAmendment{}, err
+		}
 	}
 
 	_, err = tx.Exec(`
-		DELETE FROM orders_detail
-		WHERE OD_OrderId = ?`,
-		id,
+		UPDATE orders
+		SET Order_Total = ?, Order_AddId = COALESCE(NULLIF(?, 0), Order_AddId), Order_StockReserved = 1
+		WHERE Order_Id = ?`,
+		o.Total, o.AddId, o.Id,
 	)
 	if err != nil {
-		tx.Rollback()
-		return err
+		return models.OrderAmendment{}, err
 	}
 
+	amendment := models.OrderAmendment{
+		OrderId:     o.Id,
+		Lines:       nonNilChanges(changes),
+		AddId:       o.AddId,
+		TotalBefore: totalBefore,
+		TotalAfter:  o.Total,
+		Actor:       o.UserUUID,
+	}
+	lines, err := json.Marshal(amendment.Lines)
+	if err != nil {
+		return models.OrderAmendment{}, err
+	}
 	_, err = tx.Exec(`
-		DELETE FROM orders
-		WHERE Order_Id = ? AND Order_UserUUID = ?`,
-		id, userUUID,
+		INSERT INTO order_amendments (OA_OrderId, OA_Lines, OA_AddId, OA_TotalBefore, OA_TotalAfter, OA_Actor, OA_Date)
+		VALUES (?, ?, NULLIF(?, 0), ?, ?, ?, NOW())`,
+		o.Id, string(lines), o.AddId, totalBefore, o.Total, o.UserUUID,
 	)
 	if err != nil {
-		tx.Rollback()
-		return err
+		return models.OrderAmendment{}, err
 	}
 
-	return tx.Commit()
+	return amendment, tx.Commit()
+}
+
+// GetAmendments returns the amendments of an order, oldest first
+func (r *repositorySQL) GetAmendments(id int) ([]models.OrderAmendment, error) {
+	rows, err := r.db.Query(`
+		SELECT OA_OrderId, OA_Lines, COALESCE(OA_AddId, 0), OA_TotalBefore, OA_TotalAfter, COALESCE(OA_Actor, ''), OA_Date
+		FROM order_amendments
+		WHERE OA_OrderId = ?
+		ORDER BY OA_Date, OA_Id`,
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var amendments []models.OrderAmendment
+	for rows.Next() {
+		var a models.OrderAmendment
+		var lines []byte
+		if err := rows.Scan(&a.OrderId, &lines, &a.AddId, &a.TotalBefore, &a.TotalAfter, &a.Actor, &a.Date); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(lines, &a.Lines); err != nil {
+			return nil, err
+		}
+		amendments = append(amendments, a)
+	}
+	return amendments, rows.Err()
+}
+
+func nonNilChanges(changes []models.OrderLineChange) []models.OrderLineChange {
+	if changes == nil {
+		return []models.OrderLineChange{}
+	}
+	return changes
 }
@@ -5,6 +5,8 @@ import (
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/ddessilvestri/ecommerce-go/routers/route"
+	"github.com/ddessilvestri/ecommerce-go/tools"
 )
 
 type Router struct {
@@ -18,10 +20,31 @@ func NewRouter(db *sql.DB) *Router {
 	return &Router{handler: handler}
 }
 
+// RegisterRoutes declares the order endpoints on the route table
+func RegisterRoutes(table *route.Table, db *sql.DB) {
+	r := NewRouter(db)
+	table.Handle(route.GET, "/order", r.Get)
+	table.Handle(route.GET, "/order/{id}", r.Get)
+	table.Handle(route.POST, "/order", r.Post)
+	table.Handle(route.POST, "/order/quote", r.Quote)
+	table.Handle(route.PUT, "/order/{id}", r.Put)
+	table.Handle(route.DELETE, "/order/{id}", r.Delete)
+	table.Handle(route.POST, "/order/{id}/amend", r.Amend)
+	table.Handle(route.GET, "/order/{id}/amendments", r.Amendments)
+	table.Handle(route.POST, "/order/{id}/cancel", r.Cancel)
+	table.Handle(route.GET, "/order/{id}/timeline", r.Timeline)
+	table.Handle(route.POST, "/order/{id}/status", r.Status).Require(models.RoleAdmin)
+	table.Handle(route.GET, "/admin/orders/{id}/timeline", r.AdminTimeline).Require(models.RoleAdmin)
+}
+
 func (r *Router) Post(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
 	return r.handler.Post(requestWithContext)
 }
 
+func (r *Router) Quote(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	return r.handler.Quote(requestWithContext)
+}
+
 func (r *Router) Get(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
 	return r.handler.Get(requestWithContext)
 }
@@ -30,6 +53,34 @@ func (r *Router) Put(requestWithContext models.RequestWithContext) *events.APIGa
 	return r.handler.Put(requestWithContext)
 }
 
+func (r *Router) Patch(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	return tools.ErrorResponse(requestWithContext, models.NewMethodNotAllowedError("not implemented"))
+}
+
 func (r *Router) Delete(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
 	return r.handler.Delete(requestWithContext)
 }
+
+func (r *Router) Status(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	return r.handler.Status(requestWithContext)
+}
+
+func (r *Router) Timeline(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	return r.handler.Timeline(requestWithContext)
+}
+
+func (r *Router) AdminTimeline(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	return r.handler.AdminTimeline(requestWithContext)
+}
+
+func (r *Router) Cancel(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	return r.handler.Cancel(requestWithContext)
+}
+
+func (r *Router) Amend(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	return r.handler.Amend(requestWithContext)
+}
+
+func (r *Router) Amendments(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	return r.handler.Amendments(requestWithContext)
+}
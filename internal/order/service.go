@@ -1,10 +1,10 @@
 package order
 
 import (
-	"errors"
 	"fmt"
 
 	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/ddessilvestri/ecommerce-go/tools"
 )
 
 type Service struct {
@@ -15,38 +15,67 @@ func NewService(repo Storage) *Service {
 	return &Service{repo: repo}
 }
 
+// Create prices the order from the catalog and stores the computed amounts.
+// Prices and total sent by the client are only checked against the quote, never stored.
+// The address must be one of the user's; the repository checks it in the transaction that stores the order.
 func (s *Service) Create(o models.Orders) (int64, error) {
-	fmt.Printf("DEBUG: Received order with Total: %v (type: %T)\n", o.Total, o.Total)
-
-	if o.Total <= 0 {
-		return 0, errors.New("order total must be > 0")
-	}
 	if o.UserUUID == "" {
-		return 0, errors.New("user UUID must be provided")
+		return 0, ErrMissingUserUUID
 	}
 	if o.AddId <= 0 {
-		return 0, errors.New("address ID must be provided")
+		return 0, ErrMissingAddress
+	}
+	if err := validateLines(o); err != nil {
+		return 0, err
+	}
+
+	// Priced by the repository from the rows it locks to take the stock
+	return s.repo.Insert(o, pricePlan(o))
+}
+
+// Quote returns the order priced at the current catalog prices, without storing it
+func (s *Service) Quote(o models.Orders) (models.Orders, error) {
+	if err := validateLines(o); err != nil {
+		return models.Orders{}, err
+	}
+	return s.quote(o)
+}
+
+// validateLines checks the total and lines sent by the client before anything is priced
+func validateLines(o models.Orders) error {
+	if o.Total < 0 {
+		return ErrInvalidTotal
 	}
 	if len(o.OrderDetails) == 0 {
-		return 0, errors.New("order must have at least one order detail")
+		return ErrMissingDetails
 	}
-	for _, detail := range o.OrderDetails {
+	for i, detail := range o.OrderDetails {
+		field := fmt.Sprintf("OrderDetails[%d]", i)
 		if detail.ProdId <= 0 {
-			return 0, errors.New("product ID must be provided")
+			return models.NewFieldError(field+".prodId", "product ID must be provided")
 		}
 		if detail.Quantity <= 0 {
-			return 0, errors.New("quantity must be greater than 0")
+			return models.NewFieldError(field+".quantity", "quantity must be greater than 0")
 		}
-		if detail.Price <= 0 {
-			return 0, errors.New("price must be greater than 0")
+		if detail.Price < 0 {
+			return models.NewFieldError(field+".price", "price cannot be negative")
 		}
 	}
-
-	return s.repo.Insert(o)
+	return nil
 }
 
-func (s *Service) GetAllByUserUUID(page, limit int, fromDate, toDate string, userUUID string) ([]models.Orders, error) {
-	return s.repo.GetAllByUserUUID(page, limit, fromDate, toDate, userUUID)
+func (s *Service) GetAllByUserUUID(userUUID string, q tools.Query) (models.Page[models.Orders], error) {
+	orders, err := s.repo.GetAllByUserUUID(userUUID, q.Fetch())
+	if err != nil {
+		return models.Page[models.Orders]{}, err
+	}
+	total, err := s.repo.CountByUserUUID(userUUID, q)
+	if err != nil {
+		return models.Page[models.Orders]{}, err
+	}
+	return tools.NewListPage(orders, q, total, func(o models.Orders) (interface{}, int) {
+		return sortValue(o, q.SortBy), o.Id
+	}), nil
 }
 
 func (s *Service) GetById(id int) (models.Orders, error) {
@@ -61,16 +90,17 @@ func (s *Service) GetByIdWithUserValidation(id int, userUUID string) (models.Ord
 
 	// Validate that the order belongs to the authenticated user
 	if order.UserUUID != userUUID {
-		return models.Orders{}, errors.New("order not found or access denied")
+		return models.Orders{}, ErrOrderNotFound
 	}
 
 	return order, nil
 }
 
-func (s *Service) Update(o models.Orders) error {
-	return s.repo.Update(o)
-}
-
-func (s *Service) Delete(id int, userUUID string) error {
-	return s.repo.Delete(id, userUUID)
-}
+var ErrInvalidTotal = models.NewFieldError("orderTotal", "order total cannot be negative")
+var ErrMissingUserUUID = models.NewUnauthorizedError("user UUID must be provided")
+var ErrMissingAddress = models.NewFieldError("orderAddId", "address ID must be provided")
+var ErrAddressNotFound = models.NewFieldError("orderAddId", "address not found")
+var ErrMissingDetails = models.NewFieldError("OrderDetails", "order must have at least one order detail")
+var ErrOrderNotFound = models.NewNotFoundError("order not found or access denied")
+var ErrPriceChanged = models.NewConflictError("prices changed: review the current amounts and resubmit")
+var ErrInsufficientStock = models.NewConflictError("not enough stock for some lines: lower their quantity and resubmit")
@@ -0,0 +1,352 @@
+package order
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pricingStore serves catalog prices and records inserts; other Storage methods are not used
+type pricingStore struct {
+	Storage
+	catalog  map[int]models.Product
+	inserted *models.Orders
+	reads    int // unlocked price reads, through GetProductPrices
+}
+
+func newPricingService() (*Service, *pricingStore) {
+	store := &pricingStore{catalog: map[int]models.Product{
+		1: {Id: 1, Price: 999, Status: models.ProductActive},
+		2: {Id: 2, Price: 0.1, Status: models.ProductActive},
+		3: {Id: 3, Price: 20, Status: models.ProductArchived},
+	}}
+	return NewService(store), store
+}
+
+func (s *pricingStore) GetProductPrices(ids []int) (map[int]models.Product, error) {
+	s.reads++
+	found := map[int]models.Product{}
+	for _, id := range ids {
+		if p, ok := s.catalog[id]; ok {
+			found[id] = p
+		}
+	}
+	return found, nil
+}
+
+// Insert prices o from the catalog the way the repository does from the rows it locks
+func (s *pricingStore) Insert(o models.Orders, price PricePlan) (int64, error) {
+	quoted, err := price(s.catalog)
+	if err != nil {
+		return 0, err
+	}
+	s.inserted = &quoted
+	return 7, nil
+}
+
+func newOrder(details ...models.OrdersDetails) models.Orders {
+	return models.Orders{UserUUID: "user-123", AddId: 1, OrderDetails: details}
+}
+
+// Test the stored amounts come from the catalog, summed in cents
+func TestCreatePricesFromCatalog(t *testing.T) {
+	service, store := newPricingService()
+
+	id, err := service.Create(newOrder(models.OrdersDetails{ProdId: 1, Quantity: 2}, models.OrdersDetails{ProdId: 2, Quantity: 3}))
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), id)
+	assert.Equal(t, 1998.3, store.inserted.Total)
+	assert.Equal(t, 999.0, store.inserted.OrderDetails[0].Price)
+	assert.Equal(t, 1998.0, store.inserted.OrderDetails[0].LineTotal)
+	assert.Equal(t, 0.3, store.inserted.OrderDetails[1].LineTotal)
+	assert.Zero(t, store.reads, "prices are only read with the stock lock")
+}
+
+// Test client amounts that disagree with the catalog are refused with the current ones
+func TestCreateRejectsStaleAmounts(t *testing.T) {
+	service, store := newPricingService()
+
+	order := newOrder(models.OrdersDetails{ProdId: 1, Quantity: 1, Price: 0.01})
+	order.Total = 0.01
+	_, err := service.Create(order)
+
+	var domainErr *models.DomainError
+	require.ErrorAs(t, err, &domainErr)
+	assert.ErrorIs(t, err, ErrPriceChanged)
+	assert.Equal(t, []models.FieldError{
+		{Field: "OrderDetails[0].price", Message: "current price is 999.00"},
+		{Field: "orderTotal", Message: "current total is 999.00"},
+	}, domainErr.Fields)
+	assert.Nil(t, store.inserted)
+
+	order = newOrder(models.OrdersDetails{ProdId: 2, Quantity: 3, Price: 0.1})
+	order.Total = 0.3
+	_, err = service.Create(order)
+	assert.NoError(t, err, "matching amounts are accepted")
+}
+
+// Test unknown and withdrawn products cannot be ordered
+func TestQuoteRejectsUnavailableProducts(t *testing.T) {
+	service, _ := newPricingService()
+
+	_, err := service.Quote(newOrder(models.OrdersDetails{ProdId: 3, Quantity: 1}))
+	assert.ErrorContains(t, err, "product 3 is not for sale")
+	_, err = service.Quote(newOrder(models.OrdersDetails{ProdId: 99, Quantity: 1}))
+	assert.ErrorContains(t, err, "product 99 not found")
+}
+
+// Test every short line is listed, counting all the lines of the same product
+func TestCheckStock(t *testing.T) {
+	details := []models.OrdersDetails{
+		{ProdId: 1, Quantity: 2},
+		{ProdId: 2, Quantity: 1},
+		{ProdId: 1, Quantity: 2},
+		{ProdId: 3, Quantity: 1},
+	}
+	assert.Equal(t, map[int]int{1: 4, 2: 1, 3: 1}, stockNeeded(details))
+	assert.Equal(t, []int{1, 2, 3}, sortedIds(stockNeeded(details)))
+
+	err := checkStock(details, stockNeeded(details), map[int]int{1: 3, 2: 1})
+	var domainErr *models.DomainError
+	require.ErrorAs(t, err, &domainErr)
+	assert.ErrorIs(t, err, ErrInsufficientStock)
+	assert.Equal(t, models.ErrKindConflict, domainErr.Kind)
+	assert.Equal(t, []models.FieldError{
+		{Field: "OrderDetails[0].quantity", Message: "product 1: 4 needed, 3 in stock"},
+		{Field: "OrderDetails[2].quantity", Message: "product 1: 4 needed, 3 in stock"},
+		{Field: "OrderDetails[3].quantity", Message: "product 3: 1 needed, 0 in stock"},
+	}, domainErr.Fields)
+
+	assert.NoError(t, checkStock(details, stockNeeded(details), map[int]int{1: 4, 2: 5, 3: 1}))
+}
+
+// statusStore keeps orders and their timelines in memory; other Storage methods are not used
+type statusStore struct {
+	Storage
+	orders    map[int]models.Orders
+	history   map[int][]models.OrderStatusChange
+	restocked []int
+}
+
+func newStatusService() (*Service, *statusStore) {
+	store := &statusStore{
+		orders: map[int]models.Orders{
+			1: {Id: 1, UserUUID: "user-123", Status: models.OrderStatusPending},
+			2: {Id: 2, UserUUID: "user-123", Status: models.OrderStatusShipped},
+		},
+		history: map[int][]models.OrderStatusChange{},
+	}
+	return NewService(store), store
+}
+
+func (s *statusStore) GetById(id int) (models.Orders, error) {
+	o, ok := s.orders[id]
+	if !ok {
+		return models.Orders{}, sql.ErrNoRows
+	}
+	return o, nil
+}
+
+func (s *statusStore) ChangeStatus(id int, change models.OrderStatusChange, restock bool) error {
+	if restock {
+		s.restocked = append(s.restocked, id)
+	}
+	o := s.orders[id]
+	o.Status = change.To
+	s.orders[id] = o
+	s.history[id] = append(s.history[id], change)
+	return nil
+}
+
+func (s *statusStore) GetStatusHistory(id int) ([]models.OrderStatusChange, error) {
+	return s.history[id], nil
+}
+
+// Test an order walks the lifecycle with each change recorded in its timeline
+func TestChangeStatus(t *testing.T) {
+	service, _ := newStatusService()
+
+	for _, status := range []string{models.OrderStatusPaid, models.OrderStatusProcessing, models.OrderStatusShipped} {
+		_, err := service.ChangeStatus(1, models.OrderStatusUpdate{Status: status}, "admin-1", models.RoleAdmin)
+		require.NoError(t, err)
+	}
+	timeline, err := service.ChangeStatus(1, models.OrderStatusUpdate{Status: models.OrderStatusDelivered, Note: " left at the door "}, "admin-1", models.RoleAdmin)
+	require.NoError(t, err)
+
+	assert.Equal(t, models.OrderStatusDelivered, timeline.Status)
+	require.Len(t, timeline.History, 4)
+	assert.Equal(t, models.OrderStatusChange{
+		From: models.OrderStatusShipped, To: models.OrderStatusDelivered, Actor: "admin-1", ActorRole: "admin", Note: "left at the door",
+	}, timeline.History[3])
+}
+
+// Test transitions outside the state machine are refused
+func TestChangeStatusRejectsInvalidTransitions(t *testing.T) {
+	service, store := newStatusService()
+
+	_, err := service.ChangeStatus(2, models.OrderStatusUpdate{Status: models.OrderStatusCancelled}, "admin-1", models.RoleAdmin)
+	assert.EqualError(t, err, "order cannot move from shipped to cancelled: allowed delivered")
+	_, err = service.ChangeStatus(1, models.OrderStatusUpdate{Status: "lost"}, "admin-1", models.RoleAdmin)
+	assert.ErrorIs(t, err, ErrUnknownOrderStatus)
+	_, err = service.ChangeStatus(9, models.OrderStatusUpdate{Status: models.OrderStatusPaid}, "admin-1", models.RoleAdmin)
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+	assert.Empty(t, store.history)
+
+	assert.False(t, canTransition(models.OrderStatusRefunded, models.OrderStatusPaid))
+	assert.True(t, canTransition(models.OrderStatusDelivered, models.OrderStatusRefunded))
+}
+
+// Test customers only see the timeline of their own orders
+func TestTimelineOwnership(t *testing.T) {
+	service, _ := newStatusService()
+
+	timeline, err := service.Timeline(1, "user-123")
+	require.NoError(t, err)
+	assert.Equal(t, []models.OrderStatusChange{}, timeline.History)
+
+	_, err = service.Timeline(1, "user-456")
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+}
+
+// Test admins read the timeline of any order, such as one they just moved
+func TestAdminTimeline(t *testing.T) {
+	service, _ := newStatusService()
+
+	_, err := service.ChangeStatus(1, models.OrderStatusUpdate{Status: models.OrderStatusPaid}, "admin-1", models.RoleAdmin)
+	require.NoError(t, err)
+
+	timeline, err := service.AdminTimeline(1)
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderStatusPaid, timeline.Status)
+	require.Len(t, timeline.History, 1)
+	assert.Equal(t, "admin-1", timeline.History[0].Actor)
+
+	_, err = service.AdminTimeline(9)
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+}
+
+// Test a customer cancellation restocks, keeps the reason and leaves the order readable
+func TestCancel(t *testing.T) {
+	service, store := newStatusService()
+
+	timeline, err := service.Cancel(1, "user-123", " wrong size ")
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderStatusCancelled, timeline.Status)
+	assert.Equal(t, "wrong size", timeline.History[0].Note)
+	assert.Equal(t, "customer", timeline.History[0].ActorRole)
+	assert.Equal(t, []int{1}, store.restocked)
+
+	order, err := service.GetByIdWithUserValidation(1, "user-123")
+	require.NoError(t, err)
+	assert.Equal(t, models.OrderStatusCancelled, order.Status)
+}
+
+// Test cancellation needs a reason, the owner and an order that hasn't shipped
+func TestCancelRejections(t *testing.T) {
+	service, store := newStatusService()
+
+	_, err := service.Cancel(1, "user-123", "  ")
+	assert.ErrorIs(t, err, ErrMissingCancelReason)
+	_, err = service.Cancel(1, "user-456", "changed my mind")
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+	_, err = service.Cancel(2, "user-123", "changed my mind")
+	assert.ErrorIs(t, err, ErrCancelAfterShipment)
+	assert.Empty(t, store.restocked)
+
+	_, err = service.Cancel(1, "user-123", "changed my mind")
+	require.NoError(t, err)
+	_, err = service.Cancel(1, "user-123", "changed my mind")
+	assert.EqualError(t, err, "order cannot move from cancelled to cancelled: allowed none, the order is closed")
+}
+
+// Test stock only comes back when an order is called off before it ships
+func TestReleasesStock(t *testing.T) {
+	assert.True(t, releasesStock(models.OrderStatusPaid, models.OrderStatusCancelled))
+	assert.True(t, releasesStock(models.OrderStatusProcessing, models.OrderStatusRefunded))
+	assert.False(t, releasesStock(models.OrderStatusDelivered, models.OrderStatusRefunded))
+	assert.False(t, releasesStock(models.OrderStatusPaid, models.OrderStatusProcessing))
+}
+
+// amendStore serves one order on top of the catalog prices and records the amendment handed to the repository
+type amendStore struct {
+	*pricingStore
+	order   models.Orders
+	amended *models.Orders
+}
+
+func newAmendService(status string) (*Service, *amendStore) {
+	_, prices := newPricingService()
+	store := &amendStore{pricingStore: prices, order: models.Orders{
+		Id: 5, UserUUID: "user-123", AddId: 1, Status: status, Total: 1998.3,
+		OrderDetails: []models.OrdersDetails{{ProdId: 1, Quantity: 2, Price: 999}, {ProdId: 2, Quantity: 3, Price: 0.1}},
+	}}
+	return NewService(store), store
+}
+
+func (s *amendStore) GetById(id int) (models.Orders, error) {
+	if id != s.order.Id {
+		return models.Orders{}, sql.ErrNoRows
+	}
+	return s.order, nil
+}
+
+func (s *amendStore) Amend(o models.Orders, status string, price PricePlan) (models.OrderAmendment, error) {
+	quoted, err := price(s.catalog)
+	if err != nil {
+		return models.OrderAmendment{}, err
+	}
+	s.amended = &quoted
+	return models.OrderAmendment{OrderId: quoted.Id, Lines: diffLines(s.order.OrderDetails, quoted.OrderDetails), TotalAfter: quoted.Total}, nil
+}
+
+// Test the diff only lists products whose quantity changed, over all their lines
+func TestDiffLines(t *testing.T) {
+	before := []models.OrdersDetails{{ProdId: 1, Quantity: 2}, {ProdId: 2, Quantity: 3}, {ProdId: 4, Quantity: 1}}
+	after := []models.OrdersDetails{{ProdId: 2, Quantity: 1}, {ProdId: 1, Quantity: 1}, {ProdId: 1, Quantity: 1}, {ProdId: 3, Quantity: 5}}
+
+	changes := diffLines(before, after)
+	assert.Equal(t, []models.OrderLineChange{
+		{ProdId: 2, From: 3, To: 1},
+		{ProdId: 3, From: 0, To: 5},
+		{ProdId: 4, From: 1, To: 0},
+	}, changes)
+	assert.Equal(t, map[int]int{2: -2, 3: 5, 4: -1}, stockDelta(changes))
+	assert.Equal(t, map[int]int{3: 5}, extraStock(stockDelta(changes)))
+}
+
+// Test an amendment is repriced from the catalog and handed over with the status it was checked in
+func TestAmend(t *testing.T) {
+	service, store := newAmendService(models.OrderStatusPending)
+
+	amendment, err := service.Amend(5, "user-123", models.Orders{OrderDetails: []models.OrdersDetails{{ProdId: 1, Quantity: 1}}})
+	require.NoError(t, err)
+	assert.Equal(t, []models.OrderLineChange{{ProdId: 1, From: 2, To: 1}, {ProdId: 2, From: 3, To: 0}}, amendment.Lines)
+	assert.Equal(t, 999.0, store.amended.Total)
+	assert.Equal(t, 0, store.amended.AddId, "the address is unchanged")
+	assert.Equal(t, "user-123", store.amended.UserUUID)
+	assert.Zero(t, store.reads, "prices are only read with the stock lock")
+}
+
+// Test amendments are refused outside editable statuses, for other users and when nothing changes
+func TestAmendRejections(t *testing.T) {
+	lines := models.Orders{OrderDetails: []models.OrdersDetails{{ProdId: 1, Quantity: 1}}}
+
+	service, store := newAmendService(models.OrderStatusPaid)
+	_, err := service.Amend(5, "user-123", lines)
+	assert.EqualError(t, err, "order is paid: only pending orders can be amended")
+
+	service, store = newAmendService(models.OrderStatusPending)
+	_, err = service.Amend(5, "user-456", lines)
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+
+	unchanged := models.Orders{AddId: 1, OrderDetails: []models.OrdersDetails{{ProdId: 2, Quantity: 3}, {ProdId: 1, Quantity: 2}}}
+	_, err = service.Amend(5, "user-123", unchanged)
+	assert.ErrorIs(t, err, ErrNothingToAmend)
+
+	_, err = service.Amend(5, "user-123", models.Orders{OrderDetails: []models.OrdersDetails{{ProdId: 3, Quantity: 1}}})
+	assert.ErrorContains(t, err, "product 3 is not for sale")
+	assert.Nil(t, store.amended)
+}
@@ -0,0 +1,159 @@
+package order
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ddessilvestri/ecommerce-go/models"
+)
+
+// transitions lists the statuses an order may move to from each status.
+// Orders can be cancelled until they ship; paid orders can be refunded until they are delivered and after.
+var transitions = map[string][]string{
+	models.OrderStatusPending:    {models.OrderStatusPaid, models.OrderStatusCancelled},
+	models.OrderStatusPaid:       {models.OrderStatusProcessing, models.OrderStatusCancelled, models.OrderStatusRefunded},
+	models.OrderStatusProcessing: {models.OrderStatusShipped, models.OrderStatusCancelled, models.OrderStatusRefunded},
+	models.OrderStatusShipped:    {models.OrderStatusDelivered},
+	models.OrderStatusDelivered:  {models.OrderStatusRefunded},
+	models.OrderStatusCancelled:  {},
+	models.OrderStatusRefunded:   {},
+}
+
+const maxStatusNoteLength = 255 // size of OSH_Note
+
+// releasesStock reports whether moving from one status to another gives the order's quantities back:
+// the order is called off before its goods left the warehouse
+func releasesStock(from, to string) bool {
+	beforeShipment := from == models.OrderStatusPending || from == models.OrderStatusPaid || from == models.OrderStatusProcessing
+	return beforeShipment && (to == models.OrderStatusCancelled || to == models.OrderStatusRefunded)
+}
+
+// canTransition reports whether an order in status from may move to status to
+func canTransition(from, to string) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ChangeStatus moves an order to a new status on behalf of actor and returns the updated timeline
+func (s *Service) ChangeStatus(id int, update models.OrderStatusUpdate, actor string, role models.Role) (models.OrderTimeline, error) {
+	if _, ok := transitions[update.Status]; !ok {
+		return models.OrderTimeline{}, ErrUnknownOrderStatus
+	}
+	if len(update.Note) > maxStatusNoteLength {
+		return models.OrderTimeline{}, ErrStatusNoteTooLong
+	}
+
+	order, err := s.repo.GetById(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.OrderTimeline{}, ErrOrderNotFound
+	}
+	if err != nil {
+		return models.OrderTimeline{}, err
+	}
+	if !canTransition(order.Status, update.Status) {
+		return models.OrderTimeline{}, errTransition(order.Status, update.Status)
+	}
+
+	change := models.OrderStatusChange{
+		From:      order.Status,
+		To:        update.Status,
+		Actor:     actor,
+		ActorRole: string(role),
+		Note:      strings.TrimSpace(update.Note),
+	}
+	if err := s.repo.ChangeStatus(id, change, releasesStock(change.From, change.To)); err != nil {
+		return models.OrderTimeline{}, err
+	}
+	return s.timeline(id, update.Status)
+}
+
+// Cancel lets a customer call off one of their orders before it ships. The stock comes back,
+// the reason is kept in the timeline and the order stays readable as cancelled.
+func (s *Service) Cancel(id int, userUUID, reason string) (models.OrderTimeline, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return models.OrderTimeline{}, ErrMissingCancelReason
+	}
+	if len(reason) > maxStatusNoteLength {
+		return models.OrderTimeline{}, ErrStatusNoteTooLong
+	}
+
+	order, err := s.GetByIdWithUserValidation(id, userUUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.OrderTimeline{}, ErrOrderNotFound
+	}
+	if err != nil {
+		return models.OrderTimeline{}, err
+	}
+	if order.Status == models.OrderStatusShipped || order.Status == models.OrderStatusDelivered {
+		return models.OrderTimeline{}, ErrCancelAfterShipment
+	}
+	if !canTransition(order.Status, models.OrderStatusCancelled) {
+		return models.OrderTimeline{}, errTransition(order.Status, models.OrderStatusCancelled)
+	}
+
+	change := models.OrderStatusChange{
+		From:      order.Status,
+		To:        models.OrderStatusCancelled,
+		Actor:     userUUID,
+		ActorRole: string(models.RoleCustomer),
+		Note:      reason,
+	}
+	if err := s.repo.ChangeStatus(id, change, true); err != nil {
+		return models.OrderTimeline{}, err
+	}
+	return s.timeline(id, models.OrderStatusCancelled)
+}
+
+// Timeline returns the status history of one of the user's orders
+func (s *Service) Timeline(id int, userUUID string) (models.OrderTimeline, error) {
+	order, err := s.GetByIdWithUserValidation(id, userUUID)
+	if err != nil {
+		return models.OrderTimeline{}, err
+	}
+	return s.timeline(id, order.Status)
+}
+
+// AdminTimeline returns the status history of any order, for the admins who move it along
+func (s *Service) AdminTimeline(id int) (models.OrderTimeline, error) {
+	order, err := s.repo.GetById(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.OrderTimeline{}, ErrOrderNotFound
+	}
+	if err != nil {
+		return models.OrderTimeline{}, err
+	}
+	return s.timeline(id, order.Status)
+}
+
+func (s *Service) timeline(id int, status string) (models.OrderTimeline, error) {
+	history, err := s.repo.GetStatusHistory(id)
+	if err != nil {
+		return models.OrderTimeline{}, err
+	}
+	if history == nil {
+		history = []models.OrderStatusChange{}
+	}
+	return models.OrderTimeline{OrderId: id, Status: status, History: history}, nil
+}
+
+// errTransition is the 409 for a transition the state machine doesn't allow, naming the allowed ones
+func errTransition(from, to string) error {
+	allowed := strings.Join(transitions[from], ", ")
+	if allowed == "" {
+		allowed = "none, the order is closed"
+	}
+	return models.NewConflictError(fmt.Sprintf("order cannot move from %s to %s: allowed %s", from, to, allowed))
+}
+
+var ErrUnknownOrderStatus = models.NewFieldError("status", "invalid status: use pending, paid, processing, shipped, delivered, cancelled or refunded")
+var ErrStatusNoteTooLong = models.NewFieldError("note", fmt.Sprintf("note is longer than %d characters", maxStatusNoteLength))
+var ErrMissingCancelReason = models.NewFieldError("reason", "a reason is required to cancel an order")
+var ErrCancelAfterShipment = models.NewConflictError("order has shipped and can no longer be cancelled: ask for a refund instead")
+var ErrOrderStatusChanged = models.NewConflictError("order status changed meanwhile: reload the order and retry")
@@ -0,0 +1,60 @@
+package order
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ddessilvestri/ecommerce-go/models"
+)
+
+// stockNeeded adds up the quantity ordered of each product, over all the lines that order it
+func stockNeeded(details []models.OrdersDetails) map[int]int {
+	needed := map[int]int{}
+	for _, d := range details {
+		needed[d.ProdId] += d.Quantity
+	}
+	return needed
+}
+
+// sortedIds returns the product ids in ascending order, the order their rows are locked in,
+// so concurrent orders for the same products queue instead of deadlocking
+func sortedIds(needed map[int]int) []int {
+	ids := make([]int, 0, len(needed))
+	for id := range needed {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// checkStock refuses the order when a product has less stock than needed, the units the order
+// (or an amendment of it) takes per product. The 409 lists every short line of details,
+// so the client can lower all of them at once.
+func checkStock(details []models.OrdersDetails, needed, inStock map[int]int) error {
+	var fields []models.FieldError
+	for i, d := range details {
+		if needed[d.ProdId] == 0 || needed[d.ProdId] <= inStock[d.ProdId] {
+			continue
+		}
+		fields = append(fields, models.FieldError{
+			Field:   fmt.Sprintf("OrderDetails[%d].quantity", i),
+			Message: fmt.Sprintf("product %d: %d needed, %d in stock", d.ProdId, needed[d.ProdId], max(inStock[d.ProdId], 0)),
+		})
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	err := *ErrInsufficientStock
+	err.Fields = fields
+	return &err
+}
+
+// stockOf keeps the stock of each locked product, keyed by id
+func stockOf(catalog map[int]models.Product) map[int]int {
+	stock := make(map[int]int, len(catalog))
+	for id, p := range catalog {
+		stock[id] = p.Stock
+	}
+	return stock
+}
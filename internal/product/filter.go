@@ -0,0 +1,182 @@
+package product
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/ddessilvestri/ecommerce-go/models"
+)
+
+// Filter narrows a product listing; every field that is set is ANDed into the same query
+type Filter struct {
+	Search               string
+	SearchMode           string // SearchNatural or SearchBoolean
+	CategoryId           int
+	CategorySlug         string
+	IncludeSubcategories bool
+	MinPrice             *float64
+	MaxPrice             *float64
+	InStock              bool
+	CreatedAfter         string   // "2006-01-02 15:04:05"
+	Statuses             []string // empty lists every status; set by the handler, not from the query string
+
+	match *SearchMatch // set by Service.Find from the configured search backend
+}
+
+// Full-text search modes, see https://dev.mysql.com/doc/refman/8.0/en/fulltext-search.html
+const (
+	SearchNatural = "natural"
+	SearchBoolean = "boolean"
+)
+
+// ParseFilter reads the product filter parameters of GET /product:
+// search, search_mode, categId | slugCateg, subcategories, min_price, max_price, in_stock, created_after
+func ParseFilter(query map[string]string) (Filter, error) {
+	f := Filter{
+		Search:       strings.TrimSpace(query["search"]),
+		SearchMode:   SearchNatural,
+		CategorySlug: strings.TrimSpace(query["slugCateg"]),
+	}
+
+	if val := strings.ToLower(strings.TrimSpace(query["search_mode"])); val != "" {
+		if val != SearchNatural && val != SearchBoolean {
+			return Filter{}, models.NewFieldError("search_mode", "invalid 'search_mode' parameter: use 'natural' or 'boolean'")
+		}
+		f.SearchMode = val
+	}
+
+	if val := strings.TrimSpace(query["categId"]); val != "" {
+		id, err := strconv.Atoi(val)
+		if err != nil || id <= 0 {
+			return Filter{}, models.NewFieldError("categId", "invalid 'categId' parameter")
+		}
+		f.CategoryId = id
+	}
+	if f.CategoryId != 0 && f.CategorySlug != "" {
+		return Filter{}, ErrAmbiguousCategory
+	}
+
+	var err error
+	if f.IncludeSubcategories, err = parseBool(query, "subcategories"); err != nil {
+		return Filter{}, err
+	}
+	if f.InStock, err = parseBool(query, "in_stock"); err != nil {
+		return Filter{}, err
+	}
+	if f.MinPrice, err = parsePrice(query, "min_price"); err != nil {
+		return Filter{}, err
+	}
+	if f.MaxPrice, err = parsePrice(query, "max_price"); err != nil {
+		return Filter{}, err
+	}
+	if f.MinPrice != nil && f.MaxPrice != nil && *f.MinPrice > *f.MaxPrice {
+		return Filter{}, ErrInvalidPriceRange
+	}
+
+	if val := strings.TrimSpace(query["created_after"]); val != "" {
+		t, err := time.Parse(time.RFC3339, val)
+		if err != nil {
+			t, err = time.Parse("2006-01-02", val)
+		}
+		if err != nil {
+			return Filter{}, models.NewFieldError("created_after", "invalid 'created_after' parameter").Wrap(err)
+		}
+		f.CreatedAfter = t.UTC().Format("2006-01-02 15:04:05")
+	}
+
+	return f, nil
+}
+
+func parseBool(query map[string]string, key string) (bool, error) {
+	val := strings.TrimSpace(query[key])
+	if val == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, models.NewFieldError(key, "invalid '"+key+"' parameter").Wrap(err)
+	}
+	return b, nil
+}
+
+func parsePrice(query map[string]string, key string) (*float64, error) {
+	val := strings.TrimSpace(query[key])
+	if val == "" {
+		return nil, nil
+	}
+	price, err := strconv.ParseFloat(val, 64)
+	if err != nil || price < 0 {
+		return nil, models.NewFieldError(key, "invalid '"+key+"' parameter")
+	}
+	return &price, nil
+}
+
+// Where is the SQL condition of the filter, written against products joined with category
+func (f Filter) Where() squirrel.And {
+	where := squirrel.And{}
+
+	if f.Search != "" {
+		where = append(where, f.searchMatch().Where)
+	}
+
+	switch {
+	case f.CategoryId != 0 && f.IncludeSubcategories:
+		where = append(where, squirrel.Expr("Prod_CategoryId IN ("+subtreeIds("Categ_Id")+")", f.CategoryId))
+	case f.CategoryId != 0:
+		where = append(where, squirrel.Eq{"Prod_CategoryId": f.CategoryId})
+	case f.CategorySlug != "" && f.IncludeSubcategories:
+		where = append(where, squirrel.Expr("Prod_CategoryId IN ("+subtreeIds("Categ_Path")+")", f.CategorySlug))
+	case f.CategorySlug != "":
+		where = append(where, squirrel.Eq{"Categ_Path": f.CategorySlug})
+	}
+
+	if f.MinPrice != nil {
+		where = append(where, squirrel.GtOrEq{"Prod_Price": *f.MinPrice})
+	}
+	if f.MaxPrice != nil {
+		where = append(where, squirrel.LtOrEq{"Prod_Price": *f.MaxPrice})
+	}
+	if f.InStock {
+		where = append(where, squirrel.Gt{"Prod_Stock": 0})
+	}
+	if f.CreatedAfter != "" {
+		where = append(where, squirrel.Gt{"Prod_CreatedAt": f.CreatedAfter})
+	}
+	if len(f.Statuses) > 0 {
+		where = append(where, squirrel.Eq{"Prod_Status": f.Statuses})
+	}
+
+	return where
+}
+
+// Relevance is the score of the search, 0 when there is none
+func (f Filter) Relevance() squirrel.Sqlizer {
+	if f.Search == "" {
+		return squirrel.Expr("0")
+	}
+	return f.searchMatch().Relevance
+}
+
+// searchMatch is the match set by the search backend, or the FULLTEXT one by default
+func (f Filter) searchMatch() SearchMatch {
+	if f.match != nil {
+		return *f.match
+	}
+	match := fulltextMatch(f.Search, f.SearchMode)
+	return SearchMatch{Where: match, Relevance: match}
+}
+
+// subtreeIds selects the id of the category where column = ? and of all the categories below it
+func subtreeIds(column string) string {
+	return "WITH RECURSIVE subtree AS (" +
+		"SELECT Categ_Id FROM category WHERE " + column + " = ? " +
+		"UNION ALL SELECT child.Categ_Id FROM category child JOIN subtree ON child.Categ_ParentId = subtree.Categ_Id" +
+		") SELECT Categ_Id FROM subtree"
+}
+
+// escapeLike makes user input match literally inside a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,98 @@
+package product
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func whereSql(t *testing.T, f Filter) (string, []interface{}) {
+	query, args, err := squirrel.Select("*").From("products").Where(f.Where()).ToSql()
+	require.NoError(t, err)
+	return query, args
+}
+
+// Test every filter lands in the same WHERE clause
+func TestParseFilterCombinesClauses(t *testing.T) {
+	f, err := ParseFilter(map[string]string{
+		"search":        "mouse",
+		"slugCateg":     "peripherals",
+		"min_price":     "10",
+		"max_price":     "99.5",
+		"in_stock":      "true",
+		"created_after": "2024-03-01",
+	})
+	require.NoError(t, err)
+
+	query, args := whereSql(t, f)
+	assert.Equal(t, "SELECT * FROM products WHERE (MATCH(Prod_Title, Prod_Description) AGAINST (? IN NATURAL LANGUAGE MODE) "+
+		"AND Categ_Path = ? AND Prod_Price >= ? AND Prod_Price <= ? AND Prod_Stock > ? AND Prod_CreatedAt > ?)", query)
+	assert.Equal(t, []interface{}{"mouse", "peripherals", 10.0, 99.5, 0, "2024-03-01 00:00:00"}, args)
+}
+
+// Test the storefront status restriction is part of the same WHERE clause
+func TestFilterStatuses(t *testing.T) {
+	query, args := whereSql(t, Filter{InStock: true, Statuses: []string{models.ProductActive}})
+	assert.Equal(t, "SELECT * FROM products WHERE (Prod_Stock > ? AND Prod_Status IN (?))", query)
+	assert.Equal(t, []interface{}{0, "active"}, args)
+}
+
+// Test boolean mode passes the operators through to MATCH ... AGAINST
+func TestParseFilterBooleanSearch(t *testing.T) {
+	f, err := ParseFilter(map[string]string{"search": "+wireless -mouse", "search_mode": "BOOLEAN"})
+	require.NoError(t, err)
+
+	query, args := whereSql(t, f)
+	assert.Equal(t, "SELECT * FROM products WHERE (MATCH(Prod_Title, Prod_Description) AGAINST (? IN BOOLEAN MODE))", query)
+	assert.Equal(t, []interface{}{"+wireless -mouse"}, args)
+}
+
+// Test subcategories are every category below the requested one in the tree
+func TestParseFilterSubcategories(t *testing.T) {
+	subtree := "WITH RECURSIVE subtree AS (SELECT Categ_Id FROM category WHERE %s = ? UNION ALL " +
+		"SELECT child.Categ_Id FROM category child JOIN subtree ON child.Categ_ParentId = subtree.Categ_Id) " +
+		"SELECT Categ_Id FROM subtree"
+
+	f, err := ParseFilter(map[string]string{"slugCateg": "audio", "subcategories": "1"})
+	require.NoError(t, err)
+	query, args := whereSql(t, f)
+	assert.Equal(t, "SELECT * FROM products WHERE (Prod_CategoryId IN ("+fmt.Sprintf(subtree, "Categ_Path")+"))", query)
+	assert.Equal(t, []interface{}{"audio"}, args)
+
+	f, err = ParseFilter(map[string]string{"categId": "4", "subcategories": "true"})
+	require.NoError(t, err)
+	query, args = whereSql(t, f)
+	assert.Equal(t, "SELECT * FROM products WHERE (Prod_CategoryId IN ("+fmt.Sprintf(subtree, "Categ_Id")+"))", query)
+	assert.Equal(t, []interface{}{4}, args)
+}
+
+// Test no parameters means no condition
+func TestParseFilterEmpty(t *testing.T) {
+	f, err := ParseFilter(map[string]string{"page": "2"})
+	require.NoError(t, err)
+	assert.Empty(t, f.Where())
+}
+
+// Test invalid combinations are rejected as validation errors
+func TestParseFilterInvalid(t *testing.T) {
+	for _, query := range []map[string]string{
+		{"categId": "1", "slugCateg": "audio"},
+		{"min_price": "20", "max_price": "10"},
+		{"min_price": "-1"},
+		{"max_price": "cheap"},
+		{"in_stock": "maybe"},
+		{"created_after": "yesterday"},
+		{"categId": "0"},
+		{"search": "mouse", "search_mode": "fuzzy"},
+	} {
+		_, err := ParseFilter(query)
+		require.Error(t, err, query)
+		var domainErr *models.DomainError
+		require.ErrorAs(t, err, &domainErr, query)
+		assert.Equal(t, models.ErrKindValidation, domainErr.Kind, query)
+	}
+}
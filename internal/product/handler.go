@@ -29,12 +29,12 @@ func (h *Handler) Post(requestWithContext models.RequestWithContext) *events.API
 
 	err := json.Unmarshal([]byte(body), &c)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return tools.ErrorResponse(requestWithContext, models.NewValidationError("invalid JSON body").Wrap(err))
 	}
 
 	id, err := h.service.Create(c)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "Error : "+err.Error())
+		return tools.ErrorResponse(requestWithContext, err)
 	}
 
 	return tools.CreateAPIResponse(http.StatusOK, fmt.Sprintf(`{"ProductID": %d}`, id))
@@ -47,58 +47,110 @@ func (h *Handler) Put(requestWithContext models.RequestWithContext) *events.APIG
 
 	err := json.Unmarshal([]byte(body), &c)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return tools.ErrorResponse(requestWithContext, models.NewValidationError("invalid JSON body").Wrap(err))
 	}
 
 	id := requestWithContext.RequestPathParameters()["id"]
 	idn, err := strconv.Atoi(id)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "Invalid ProductId: "+err.Error())
+		return tools.ErrorResponse(requestWithContext, ErrInvalidProductId.Wrap(err))
 	}
 
 	c.Id = idn
 
 	err = h.service.Update(c)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "Error : "+err.Error())
+		return tools.ErrorResponse(requestWithContext, err)
 	}
 
 	return tools.CreateAPIResponse(http.StatusOK, fmt.Sprintf(`{"Updated ProductId": %d}`, idn))
 }
 
-// Post handles the HTTP DELETE request to delete a category
+// Patch handles the HTTP PATCH request: a JSON Merge Patch of the product
+func (h *Handler) Patch(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+
+	id := requestWithContext.RequestPathParameters()["id"]
+	idn, err := strconv.Atoi(id)
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, ErrInvalidProductId.Wrap(err))
+	}
+
+	var p models.ProductPatch
+	if err := tools.ParseMergePatch(requestWithContext.RequestBody(), &p); err != nil {
+		return tools.ErrorResponse(requestWithContext, err)
+	}
+
+	err = h.service.Patch(idn, p)
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, err)
+	}
+
+	return tools.CreateAPIResponse(http.StatusOK, fmt.Sprintf(`{"Updated ProductId": %d}`, idn))
+}
+
+// Delete handles the HTTP DELETE request: the product is archived, not removed
 func (h *Handler) Delete(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
 
 	id := requestWithContext.RequestPathParameters()["id"]
 	idn, err := strconv.Atoi(id)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "Invalid ProductId: "+err.Error())
+		return tools.ErrorResponse(requestWithContext, ErrInvalidProductId.Wrap(err))
 	}
 
 	err = h.service.Delete(idn)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "Error : "+err.Error())
+		return tools.ErrorResponse(requestWithContext, err)
+	}
+	return tools.CreateAPIResponse(http.StatusOK, fmt.Sprintf(`{"Archived ProductId": %d}`, idn))
+
+}
+
+// Restore handles POST /product/{id}/restore, putting an archived product back on sale
+func (h *Handler) Restore(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+
+	id := requestWithContext.RequestPathParameters()["id"]
+	idn, err := strconv.Atoi(id)
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, ErrInvalidProductId.Wrap(err))
 	}
-	return tools.CreateAPIResponse(http.StatusOK, fmt.Sprintf(`{"Deleted ProductId": %d}`, idn))
 
+	err = h.service.Restore(idn)
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, err)
+	}
+	return tools.CreateAPIResponse(http.StatusOK, fmt.Sprintf(`{"Restored ProductId": %d}`, idn))
 }
 
+// Get serves the storefront: listings and lookups by id or slug only show active products (models.ProductVisible).
+// Order lines carry the product title, so order history doesn't need archived products to resolve.
 func (h *Handler) Get(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	return h.get(requestWithContext, false)
+}
+
+// AdminGet serves GET /admin/products: the same lookups and listing over every status
+func (h *Handler) AdminGet(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	return h.get(requestWithContext, true)
+}
+
+func (h *Handler) get(requestWithContext models.RequestWithContext, admin bool) *events.APIGatewayProxyResponse {
 	query := requestWithContext.RequestQueryStringParameters()
 
 	// === 1. Lookup by ID ===
 	if idStr := query["id"]; idStr != "" {
 		id, err := strconv.Atoi(idStr)
 		if err != nil || id <= 0 {
-			return tools.CreateAPIResponse(http.StatusBadRequest, "Invalid 'id' parameter")
+			return tools.ErrorResponse(requestWithContext, models.NewFieldError("id", "invalid 'id' parameter"))
 		}
 		product, err := h.service.GetById(id)
+		if err == nil && !admin && !models.ProductVisible(product.Status) {
+			err = ErrProductNotFound
+		}
 		if err != nil {
-			return tools.CreateAPIResponse(http.StatusNotFound, "Product not found: "+err.Error())
+			return tools.ErrorResponse(requestWithContext, err)
 		}
 		body, err := json.Marshal(product)
 		if err != nil {
-			return tools.CreateAPIResponse(http.StatusInternalServerError, "Error converting to JSON: "+err.Error())
+			return tools.ErrorResponse(requestWithContext, err)
 		}
 		return tools.CreateAPIResponse(http.StatusOK, string(body))
 	}
@@ -106,77 +158,67 @@ func (h *Handler) Get(requestWithContext models.RequestWithContext) *events.APIG
 	// === 2. Lookup by Slug ===
 	if slug := strings.TrimSpace(query["slug"]); slug != "" {
 		product, err := h.service.GetBySlug(slug)
+		if err == nil && !admin && !models.ProductVisible(product.Status) {
+			err = ErrProductNotFound
+		}
 		if err != nil {
-			return tools.CreateAPIResponse(http.StatusNotFound, "Product not found: "+err.Error())
+			return tools.ErrorResponse(requestWithContext, err)
 		}
 		body, err := json.Marshal(product)
 		if err != nil {
-			return tools.CreateAPIResponse(http.StatusInternalServerError, "Error converting to JSON: "+err.Error())
+			return tools.ErrorResponse(requestWithContext, err)
 		}
 		return tools.CreateAPIResponse(http.StatusOK, string(body))
 	}
 
-	// === 3. Search full-text ===
-	if search := strings.TrimSpace(query["search"]); search != "" {
-		page, limit, sortBy, order, err := tools.ParsePaginationAndSorting(query)
-		if err != nil {
-			return tools.CreateAPIResponse(http.StatusBadRequest, err.Error())
-		}
-		products, err := h.service.SearchByText(search, page, limit, sortBy, order)
-
-		if err != nil {
-			return tools.CreateAPIResponse(http.StatusInternalServerError, err.Error())
-		}
-		body, err := json.Marshal(products)
-		if err != nil {
-			return tools.CreateAPIResponse(http.StatusInternalServerError, "Error converting to JSON: "+err.Error())
-		}
-		return tools.CreateAPIResponse(http.StatusOK, string(body))
+	// === 3. Listing: search, category, price, stock and date filters combine in one query ===
+	filter, err := ParseFilter(query)
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, err)
 	}
-
-	// === 4. Filter by category ID ===
-	if catIdStr := strings.TrimSpace(query["categId"]); catIdStr != "" {
-		catId, err := strconv.Atoi(catIdStr)
-		if err != nil || catId <= 0 {
-			return tools.CreateAPIResponse(http.StatusBadRequest, "Invalid 'categId' parameter")
-		}
-		products, err := h.service.GetByCategoryId(catId)
-		if err != nil {
-			return tools.CreateAPIResponse(http.StatusInternalServerError, err.Error())
-		}
-		body, err := json.Marshal(products)
-		if err != nil {
-			return tools.CreateAPIResponse(http.StatusInternalServerError, "Error converting to JSON: "+err.Error())
-		}
-		return tools.CreateAPIResponse(http.StatusOK, string(body))
+	if !admin {
+		filter.Statuses = []string{models.ProductActive}
 	}
-
-	// === 5. Filter by category slug ===
-	if slugCateg := strings.TrimSpace(query["slugCateg"]); slugCateg != "" {
-		products, err := h.service.GetByCategorySlug(slugCateg)
-		if err != nil {
-			return tools.CreateAPIResponse(http.StatusInternalServerError, err.Error())
-		}
-		body, err := json.Marshal(products)
-		if err != nil {
-			return tools.CreateAPIResponse(http.StatusInternalServerError, "Error converting to JSON: "+err.Error())
-		}
-		return tools.CreateAPIResponse(http.StatusOK, string(body))
+	q, err := QuerySpec.Parse(searchDefaults(query))
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, err)
+	}
+	products, err := h.service.Find(filter, q)
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, err)
 	}
+	return tools.ListResponse(requestWithContext, products, q)
+}
 
-	// === 6. Default: Get all paginated ===
-	page, limit, sortBy, order, err := tools.ParsePaginationAndSorting(query)
+// Suggest answers GET /product/suggest?q=<prefix>&limit=<n> for search-as-you-type
+func (h *Handler) Suggest(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	query := requestWithContext.RequestQueryStringParameters()
+
+	limit, err := ParseSuggestLimit(query)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, err.Error())
+		return tools.ErrorResponse(requestWithContext, err)
 	}
 
-	products, err := h.service.GetAll(page, limit, sortBy, order)
+	suggestions, err := h.service.Suggest(query["q"], limit)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusInternalServerError, err.Error())
+		return tools.ErrorResponse(requestWithContext, err)
 	}
-	body, err := json.Marshal(products)
+
+	body, err := json.Marshal(suggestions)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusInternalServerError, "Error converting to JSON: "+err.Error())
+		return tools.ErrorResponse(requestWithContext, err)
+	}
+
+	response := tools.CreateAPIResponse(http.StatusOK, string(body))
+	response.Headers["Cache-Control"] = "public, max-age=60"
+	return response
+}
+
+// PurgeSearch handles DELETE /admin/search-queries?q=: the search stops being suggested
+func (h *Handler) PurgeSearch(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	search := requestWithContext.RequestQueryStringParameters()["q"]
+	if err := h.service.PurgeSearch(search); err != nil {
+		return tools.ErrorResponse(requestWithContext, err)
 	}
-	return tools.CreateAPIResponse(http.StatusOK, string(body))
+	return tools.CreateAPIResponse(http.StatusOK, fmt.Sprintf(`{"PurgedSearch": %q}`, normalizeSearch(search)))
 }
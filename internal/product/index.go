@@ -0,0 +1,493 @@
+package product
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/ddessilvestri/ecommerce-go/models"
+)
+
+const (
+	titleWeight       = 3.0  // a word in the title counts as much as three in the description
+	maxSearchHits     = 1000 // ids passed back to SQL for one search
+	minFuzzyLength    = 4    // shorter words are only matched exactly
+	fuzzyPenalty      = 0.25 // score lost per edit of a typo match
+	prefixMatchWeight = 0.8  // score of a word completed from a "term*" prefix
+)
+
+// stopwords are too common to rank on
+var stopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "the": true, "for": true, "with": true, "of": true, "to": true,
+	"in": true, "on": true, "or": true, "by": true,
+	"de": true, "la": true, "el": true, "y": true, "con": true, "para": true,
+}
+
+// MemoryIndex is an in-process inverted index over product titles and descriptions.
+// It folds plural and verb endings (stemming), matches typos through trigram candidates
+// checked by edit distance, and completes partially typed titles.
+type MemoryIndex struct {
+	mu      sync.RWMutex
+	source  func() ([]models.Product, error)
+	ttl     time.Duration
+	builtAt time.Time
+	maxHits int // best hits handed to SQL, maxSearchHits outside tests
+
+	docs     map[int]indexedDoc
+	postings map[string]map[int]float64 // stem -> product id -> weight
+	trigrams map[string]map[string]bool // trigram -> stems containing it
+	wordDocs map[string]map[int]bool    // title word -> product ids, for completion
+	words    []string                   // sorted keys of wordDocs
+}
+
+type indexedDoc struct {
+	title      string
+	listed     bool     // active products; others still match admin searches but are never completed
+	stems      []string // every stem the product is posted under
+	titleStems map[string]bool
+}
+
+// NewMemoryIndex builds lazily from source and rebuilds once the index is older than ttl
+func NewMemoryIndex(source func() ([]models.Product, error), ttl time.Duration) *MemoryIndex {
+	idx := &MemoryIndex{source: source, ttl: ttl, maxHits: maxSearchHits}
+	idx.reset()
+	return idx
+}
+
+// SetSource replaces the function the index is rebuilt from, e.g. after a reconnect
+func (idx *MemoryIndex) SetSource(source func() ([]models.Product, error)) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.source = source
+}
+
+// Load replaces the content of the index
+func (idx *MemoryIndex) Load(products []models.Product) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.reset()
+	for _, p := range products {
+		idx.add(p)
+	}
+	idx.sortWords()
+	idx.builtAt = time.Now()
+}
+
+// Upsert reindexes one product after it was created or changed
+func (idx *MemoryIndex) Upsert(p models.Product) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.builtAt.IsZero() {
+		return // the first search loads everything anyway
+	}
+	idx.remove(p.Id)
+	idx.add(p)
+	idx.sortWords()
+}
+
+// Remove drops a deleted product from the index
+func (idx *MemoryIndex) Remove(id int) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(id)
+	idx.sortWords()
+}
+
+// Match ranks the indexed products against search and hands the best ids back to SQL.
+// In boolean mode, +word is required, -word excludes and word* matches a prefix.
+// Past maxSearchHits the rest are dropped and the match is marked Capped.
+func (idx *MemoryIndex) Match(search, mode string) (SearchMatch, error) {
+	if err := idx.refresh(); err != nil {
+		return SearchMatch{}, err
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	hits, terms := idx.rank(parseSearch(search, mode == SearchBoolean))
+	if len(hits) == 0 {
+		return SearchMatch{Where: squirrel.Expr("1 = 0"), Relevance: squirrel.Expr("0")}, nil
+	}
+	capped := len(hits) > idx.maxHits
+	if capped {
+		hits = hits[:idx.maxHits]
+	}
+
+	ids := make([]int, len(hits))
+	relevance := "CASE Prod_Id"
+	args := make([]interface{}, 0, 2*len(hits))
+	for i, h := range hits {
+		ids[i] = h.id
+		relevance += " WHEN ? THEN ?"
+		args = append(args, h.id, h.score)
+	}
+	relevance += " ELSE 0 END"
+
+	return SearchMatch{
+		Where:     squirrel.Eq{"Prod_Id": ids},
+		Relevance: squirrel.Expr(relevance, args...),
+		Terms:     terms,
+		Capped:    capped,
+	}, nil
+}
+
+// Complete returns titles containing the typed words, the last one possibly unfinished.
+// Titles starting with the prefix come first, then shorter titles.
+func (idx *MemoryIndex) Complete(prefix string, limit int) ([]string, error) {
+	if err := idx.refresh(); err != nil {
+		return nil, err
+	}
+
+	words := wordPattern.FindAllString(strings.ToLower(prefix), -1)
+	if len(words) == 0 || limit <= 0 {
+		return nil, nil
+	}
+	last, before := words[len(words)-1], words[:len(words)-1]
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	candidates := map[int]bool{}
+	for i := sort.SearchStrings(idx.words, last); i < len(idx.words) && strings.HasPrefix(idx.words[i], last); i++ {
+		for id := range idx.wordDocs[idx.words[i]] {
+			candidates[id] = true
+		}
+	}
+
+	lowerPrefix := strings.ToLower(strings.TrimSpace(prefix))
+	var titles []string
+	seen := map[string]bool{}
+	for id := range candidates {
+		doc := idx.docs[id]
+		if !doc.listed || seen[doc.title] || !containsStems(doc, before) {
+			continue
+		}
+		seen[doc.title] = true
+		titles = append(titles, doc.title)
+	}
+
+	sort.Slice(titles, func(i, j int) bool {
+		si := strings.HasPrefix(strings.ToLower(titles[i]), lowerPrefix)
+		sj := strings.HasPrefix(strings.ToLower(titles[j]), lowerPrefix)
+		if si != sj {
+			return si
+		}
+		if len(titles[i]) != len(titles[j]) {
+			return len(titles[i]) < len(titles[j])
+		}
+		return titles[i] < titles[j]
+	})
+	if len(titles) > limit {
+		titles = titles[:limit]
+	}
+	return titles, nil
+}
+
+// refresh (re)loads the index when it was never built or is older than the ttl.
+// A failed rebuild keeps serving the previous content.
+func (idx *MemoryIndex) refresh() error {
+	idx.mu.RLock()
+	fresh := !idx.builtAt.IsZero() && time.Since(idx.builtAt) < idx.ttl
+	source, built := idx.source, !idx.builtAt.IsZero()
+	idx.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	products, err := source()
+	if err != nil {
+		if built {
+			fmt.Println("Search index refresh failed, serving the previous index:", err.Error())
+			return nil
+		}
+		return fmt.Errorf("search index build failed: %w", err)
+	}
+	idx.Load(products)
+	return nil
+}
+
+func (idx *MemoryIndex) reset() {
+	idx.docs = map[int]indexedDoc{}
+	idx.postings = map[string]map[int]float64{}
+	idx.trigrams = map[string]map[string]bool{}
+	idx.wordDocs = map[string]map[int]bool{}
+	idx.words = nil
+}
+
+func (idx *MemoryIndex) add(p models.Product) {
+	weights := map[string]float64{}
+	doc := indexedDoc{title: p.Title, listed: models.ProductVisible(p.Status), titleStems: map[string]bool{}}
+
+	for _, word := range wordPattern.FindAllString(strings.ToLower(p.Title), -1) {
+		if idx.wordDocs[word] == nil {
+			idx.wordDocs[word] = map[int]bool{}
+		}
+		idx.wordDocs[word][p.Id] = true
+		if stopwords[word] {
+			continue
+		}
+		s := stem(word)
+		weights[s] += titleWeight
+		doc.titleStems[s] = true
+	}
+	for _, word := range wordPattern.FindAllString(strings.ToLower(p.Description), -1) {
+		if !stopwords[word] {
+			weights[stem(word)]++
+		}
+	}
+
+	for s, weight := range weights {
+		if idx.postings[s] == nil {
+			idx.postings[s] = map[int]float64{}
+			for _, g := range trigrams(s) {
+				if idx.trigrams[g] == nil {
+					idx.trigrams[g] = map[string]bool{}
+				}
+				idx.trigrams[g][s] = true
+			}
+		}
+		idx.postings[s][p.Id] = weight
+		doc.stems = append(doc.stems, s)
+	}
+	idx.docs[p.Id] = doc
+}
+
+func (idx *MemoryIndex) remove(id int) {
+	doc, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+	for _, s := range doc.stems {
+		delete(idx.postings[s], id)
+		if len(idx.postings[s]) > 0 {
+			continue
+		}
+		delete(idx.postings, s)
+		for _, g := range trigrams(s) {
+			delete(idx.trigrams[g], s)
+			if len(idx.trigrams[g]) == 0 {
+				delete(idx.trigrams, g)
+			}
+		}
+	}
+	for word, ids := range idx.wordDocs {
+		delete(ids, id)
+		if len(ids) == 0 {
+			delete(idx.wordDocs, word)
+		}
+	}
+	delete(idx.docs, id)
+}
+
+func (idx *MemoryIndex) sortWords() {
+	idx.words = idx.words[:0]
+	for word := range idx.wordDocs {
+		idx.words = append(idx.words, word)
+	}
+	sort.Strings(idx.words)
+}
+
+// searchTerm is one word of a search
+type searchTerm struct {
+	word     string
+	prefix   bool // word*
+	required bool // +word
+	excluded bool // -word
+}
+
+// parseSearch splits a search into words; operators are only read in boolean mode
+func parseSearch(search string, boolean bool) []searchTerm {
+	var terms []searchTerm
+	for _, field := range strings.Fields(strings.ToLower(search)) {
+		var t searchTerm
+		if boolean {
+			t.required = strings.HasPrefix(field, "+")
+			t.excluded = strings.HasPrefix(field, "-")
+			t.prefix = strings.HasSuffix(field, "*")
+		}
+		for _, word := range wordPattern.FindAllString(field, -1) {
+			if stopwords[word] && !t.required {
+				continue
+			}
+			t.word = word
+			terms = append(terms, t)
+		}
+	}
+	return terms
+}
+
+type searchHit struct {
+	id    int
+	score float64
+}
+
+// rank scores every product matching the terms: exact stems count fully, typos and
+// completed prefixes less, each weighted by how rare the stem is (idf).
+// Products matching more of the words rank higher. It also returns the stems that matched.
+func (idx *MemoryIndex) rank(terms []searchTerm) ([]searchHit, []string) {
+	scores := map[int]float64{}
+	matched := map[int]int{}
+	excluded := map[int]bool{}
+	required := map[int]int{}
+	var requiredCount, rankedCount int
+	var matchedStems []string
+
+	for _, t := range terms {
+		termScores := map[int]float64{}
+		for s, weight := range idx.expand(t) {
+			if !t.excluded {
+				matchedStems = append(matchedStems, s)
+			}
+			idf := math.Log(1 + float64(len(idx.docs))/float64(len(idx.postings[s])))
+			for id, w := range idx.postings[s] {
+				termScores[id] = math.Max(termScores[id], weight*w*idf)
+			}
+		}
+
+		switch {
+		case t.excluded:
+			for id := range termScores {
+				excluded[id] = true
+			}
+			continue
+		case t.required:
+			requiredCount++
+			for id := range termScores {
+				required[id]++
+			}
+		}
+		rankedCount++
+		for id, score := range termScores {
+			scores[id] += score
+			matched[id]++
+		}
+	}
+
+	var hits []searchHit
+	for id, score := range scores {
+		if excluded[id] || required[id] < requiredCount {
+			continue
+		}
+		coverage := float64(matched[id]) / float64(rankedCount)
+		hits = append(hits, searchHit{id: id, score: math.Round(score*coverage*1000) / 1000})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+		return hits[i].id < hits[j].id
+	})
+	return hits, matchedStems
+}
+
+// expand lists the indexed stems a search word stands for, with the weight of each
+func (idx *MemoryIndex) expand(t searchTerm) map[string]float64 {
+	stems := map[string]float64{}
+	s := stem(t.word)
+	if _, ok := idx.postings[s]; ok {
+		stems[s] = 1
+	}
+
+	if t.prefix {
+		for indexed := range idx.postings {
+			if indexed != s && strings.HasPrefix(indexed, t.word) {
+				stems[indexed] = prefixMatchWeight
+			}
+		}
+	}
+
+	if len(stems) > 0 || len([]rune(s)) < minFuzzyLength {
+		return stems
+	}
+
+	// Typo: candidates share trigrams with the word, then the edit distance decides
+	maxEdits := 1
+	if len([]rune(s)) > 5 {
+		maxEdits = 2
+	}
+	checked := map[string]bool{}
+	for _, g := range trigrams(s) {
+		for candidate := range idx.trigrams[g] {
+			if checked[candidate] {
+				continue
+			}
+			checked[candidate] = true
+			if d := editDistance(s, candidate); d <= maxEdits {
+				stems[candidate] = 1 - fuzzyPenalty*float64(d)
+			}
+		}
+	}
+	return stems
+}
+
+// containsStems reports whether every word appears in the title of doc
+func containsStems(doc indexedDoc, words []string) bool {
+	for _, word := range words {
+		if !stopwords[word] && !doc.titleStems[stem(word)] {
+			return false
+		}
+	}
+	return true
+}
+
+// stem folds common English plural and verb endings: "cables" and "cable", "charging" and "charged"
+func stem(word string) string {
+	n := len(word)
+	switch {
+	case n > 4 && strings.HasSuffix(word, "ies"):
+		return word[:n-3] + "y"
+	case n > 5 && strings.HasSuffix(word, "ing"):
+		return word[:n-3]
+	case n > 4 && strings.HasSuffix(word, "ed"):
+		return word[:n-2]
+	case n > 4 && (strings.HasSuffix(word, "ches") || strings.HasSuffix(word, "shes") ||
+		strings.HasSuffix(word, "sses") || strings.HasSuffix(word, "xes") || strings.HasSuffix(word, "zes")):
+		return word[:n-2]
+	case n > 3 && strings.HasSuffix(word, "s") &&
+		!strings.HasSuffix(word, "ss") && !strings.HasSuffix(word, "us") && !strings.HasSuffix(word, "is"):
+		return word[:n-1]
+	}
+	return word
+}
+
+// trigrams of a word padded with "$", so the first and last letters weigh too
+func trigrams(word string) []string {
+	runes := []rune("$" + word + "$")
+	grams := make([]string, 0, len(runes))
+	for i := 0; i+3 <= len(runes); i++ {
+		grams = append(grams, string(runes[i:i+3]))
+	}
+	return grams
+}
+
+// editDistance counts insertions, deletions, substitutions and adjacent transpositions
+// ("mosue" is one edit from "mouse")
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(rb)]
+}
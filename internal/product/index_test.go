@@ -0,0 +1,158 @@
+package product
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var indexedProducts = []models.Product{
+	{Id: 1, Title: "Wireless Mouse", Description: "Silent clicks and a rechargeable battery", Status: models.ProductActive},
+	{Id: 2, Title: "Mouse Pad XL", Description: "Large desk mat", Status: models.ProductActive},
+	{Id: 3, Title: "Mechanical Keyboard", Description: "Hot-swappable switches, works with any wireless dongle", Status: models.ProductActive},
+	{Id: 4, Title: "USB Charging Cable", Description: "Braided cables for phones", Status: models.ProductActive},
+}
+
+func newTestIndex(t *testing.T) *MemoryIndex {
+	return NewMemoryIndex(func() ([]models.Product, error) { return indexedProducts, nil }, time.Hour)
+}
+
+// matchedIds runs a search and returns the ids in relevance order
+func matchedIds(t *testing.T, idx *MemoryIndex, search, mode string) []int {
+	match, err := idx.Match(search, mode)
+	require.NoError(t, err)
+	hits, _ := idx.rank(parseSearch(search, mode == SearchBoolean))
+	ids := []int{}
+	for _, h := range hits {
+		ids = append(ids, h.id)
+	}
+
+	// The SQL handed to the product query selects exactly these ids
+	sql, _, err := squirrel.Select("*").From("products").Where(match.Where).ToSql()
+	require.NoError(t, err)
+	if len(ids) == 0 {
+		assert.Equal(t, "SELECT * FROM products WHERE 1 = 0", sql)
+	}
+	return ids
+}
+
+// Test title matches outrank description matches
+func TestMemoryIndexRanksTitleFirst(t *testing.T) {
+	idx := newTestIndex(t)
+	assert.Equal(t, []int{1, 3}, matchedIds(t, idx, "wireless", SearchNatural))
+}
+
+// Test typos and transpositions still match
+func TestMemoryIndexTypos(t *testing.T) {
+	idx := newTestIndex(t)
+	assert.Equal(t, []int{1, 2}, matchedIds(t, idx, "mosue", SearchNatural))
+	assert.Equal(t, []int{3}, matchedIds(t, idx, "keybaord", SearchNatural))
+	assert.Empty(t, matchedIds(t, idx, "xyz", SearchNatural))
+}
+
+// Test plurals and verb endings fold onto the same stem
+func TestMemoryIndexStemming(t *testing.T) {
+	idx := newTestIndex(t)
+	assert.Equal(t, []int{4}, matchedIds(t, idx, "cable", SearchNatural))
+	assert.Equal(t, []int{4}, matchedIds(t, idx, "charged", SearchNatural))
+	assert.Equal(t, "battery", stem("batteries"))
+	assert.Equal(t, "box", stem("boxes"))
+	assert.Equal(t, "glass", stem("glass"))
+}
+
+// Test boolean operators require, exclude and complete words
+func TestMemoryIndexBooleanMode(t *testing.T) {
+	idx := newTestIndex(t)
+	assert.Equal(t, []int{3}, matchedIds(t, idx, "+wireless -mouse", SearchBoolean))
+	assert.Equal(t, []int{3}, matchedIds(t, idx, "mech*", SearchBoolean))
+	assert.Equal(t, []int{1}, matchedIds(t, idx, "+mouse +silent", SearchBoolean))
+}
+
+// Test only the best hits go back to SQL, and the match says it was capped
+func TestMemoryIndexCapsHits(t *testing.T) {
+	idx := newTestIndex(t)
+	idx.maxHits = 1
+
+	match, err := idx.Match("mouse", SearchNatural)
+	require.NoError(t, err)
+	assert.True(t, match.Capped)
+	_, args, err := squirrel.Select("*").From("products").Where(match.Where).ToSql()
+	require.NoError(t, err)
+	assert.Len(t, args, 1)
+
+	match, err = idx.Match("keyboard", SearchNatural)
+	require.NoError(t, err)
+	assert.False(t, match.Capped)
+}
+
+// Test completion of a partially typed title
+func TestMemoryIndexComplete(t *testing.T) {
+	idx := newTestIndex(t)
+
+	titles, err := idx.Complete("mou", 5)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Mouse Pad XL", "Wireless Mouse"}, titles)
+
+	titles, err = idx.Complete("wireless mo", 5)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Wireless Mouse"}, titles)
+
+	titles, err = idx.Complete("mou", 1)
+	require.NoError(t, err)
+	assert.Len(t, titles, 1)
+
+	// Archived and draft products still match searches but are never suggested
+	idx.Upsert(models.Product{Id: 1, Title: "Wireless Mouse", Status: models.ProductArchived})
+	idx.Upsert(models.Product{Id: 5, Title: "Mouse Bungee", Status: models.ProductDraft})
+	titles, err = idx.Complete("mou", 5)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Mouse Pad XL"}, titles)
+	assert.Equal(t, []int{1, 2, 5}, matchedIds(t, idx, "mouse", SearchNatural))
+}
+
+// Test writes update the index without a rebuild
+func TestMemoryIndexUpsertAndRemove(t *testing.T) {
+	idx := newTestIndex(t)
+	require.Empty(t, matchedIds(t, idx, "trackball", SearchNatural))
+
+	idx.Upsert(models.Product{Id: 5, Title: "Ergonomic Trackball"})
+	assert.Equal(t, []int{5}, matchedIds(t, idx, "trackball", SearchNatural))
+
+	idx.Upsert(models.Product{Id: 5, Title: "Ergonomic Vertical Mouse"})
+	assert.Empty(t, matchedIds(t, idx, "trackball", SearchNatural))
+
+	idx.Remove(1)
+	assert.Equal(t, []int{2, 5}, matchedIds(t, idx, "mouse", SearchNatural))
+}
+
+// Test a failed rebuild keeps the previous index, a failed first build is an error
+func TestMemoryIndexRefreshFailure(t *testing.T) {
+	calls := 0
+	idx := NewMemoryIndex(func() ([]models.Product, error) {
+		calls++
+		if calls > 1 {
+			return nil, errors.New("connection refused")
+		}
+		return indexedProducts, nil
+	}, 0)
+
+	assert.NotEmpty(t, matchedIds(t, idx, "mouse", SearchNatural))
+	assert.NotEmpty(t, matchedIds(t, idx, "mouse", SearchNatural))
+
+	broken := NewMemoryIndex(func() ([]models.Product, error) { return nil, errors.New("connection refused") }, time.Hour)
+	_, err := broken.Match("mouse", SearchNatural)
+	assert.Error(t, err)
+}
+
+// Test the transposition-aware edit distance
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("mouse", "mouse"))
+	assert.Equal(t, 1, editDistance("mosue", "mouse"))
+	assert.Equal(t, 1, editDistance("mose", "mouse"))
+	assert.Equal(t, 2, editDistance("keybord", "keyboards"))
+}
@@ -1,15 +1,25 @@
 package product
 
-import "github.com/ddessilvestri/ecommerce-go/models"
+import (
+	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/ddessilvestri/ecommerce-go/tools"
+)
 
 type Storage interface {
 	Insert(c models.Product) (int64, error)
 	Update(c models.Product) error
-	Delete(id int) error
+	Patch(id int, p models.ProductPatch) error
+	Exists(id int) bool
+	CategoryExists(id int) bool
+	SetStatus(id int, status string) error
 	GetById(id int) (models.Product, error)
-	GetByCategoryId(id int) ([]models.Product, error)
-	GetAll(page, limit int, sortBy, order string) ([]models.Product, error)
 	GetBySlug(slug string) (models.Product, error)
-	GetByCategorySlug(slug string) ([]models.Product, error)
-	SearchByText(text string, page, limit int, sortBy, order string) ([]models.Product, error)
+	Find(f Filter, q tools.Query) ([]models.Product, error)
+	Count(f Filter, q tools.Query) (int, error)
+	ListForIndex() ([]models.Product, error)
+	TitlesWithPrefix(prefix string, limit int) ([]string, error)
+	ListCategories() ([]models.Category, error)
+	PopularSearches(limit, minHits int) ([]string, error)
+	RecordSearch(search string) error
+	DeleteSearch(search string) error
 }
@@ -0,0 +1,71 @@
+package product
+
+import (
+	"strings"
+
+	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/ddessilvestri/ecommerce-go/tools"
+)
+
+// Sortable nullable columns, coalesced the same way when selected so a cursor holds the exact sort value
+const (
+	categoryIdColumn = "COALESCE(Prod_CategoryId, 0)"
+	stockColumn      = "COALESCE(Prod_Stock, 0)"
+	createdAtColumn  = "COALESCE(Prod_CreatedAt, TIMESTAMP '1000-01-01 00:00:00')"
+)
+
+// QuerySpec declares the product fields list endpoints can sort, filter and select on.
+// Nullable columns are coalesced so keyset comparisons never meet a NULL.
+var QuerySpec = tools.QuerySpec{
+	Fields: map[string]tools.Field{
+		"id":            {Column: "Prod_Id", JSON: "prodID", Type: tools.IntField, Sortable: true, Filterable: true},
+		"title":         {Column: "Prod_Title", JSON: "prodTitle", Sortable: true},
+		"description":   {Column: "COALESCE(Prod_Description, '')", JSON: "prodDescription", Sortable: true},
+		"price":         {Column: "Prod_Price", JSON: "prodPrice", Type: tools.NumberField, Sortable: true, Filterable: true},
+		"category_id":   {Column: categoryIdColumn, JSON: "prodCategId", Type: tools.IntField, Sortable: true, Filterable: true},
+		"stock":         {Column: stockColumn, JSON: "prodStock", Type: tools.IntField, Sortable: true, Filterable: true},
+		"created_at":    {Column: createdAtColumn, JSON: "prodCreatedAt", Type: tools.DateField, Sortable: true, Filterable: true},
+		"updated_at":    {Column: "Prod_Updated", JSON: "prodUpdated", Type: tools.DateField, Filterable: true},
+		"path":          {Column: "Prod_Path", JSON: "prodPath"},
+		"category_path": {Column: "Categ_Path", JSON: "categPath"},
+		"status":        {Column: "Prod_Status", JSON: "prodStatus", Filterable: true},
+		"relevance":     {Column: "relevance", JSON: "score", Type: tools.NumberField, Sortable: true},
+		"snippets":      {JSON: "snippets"},
+	},
+	IdColumn:     "Prod_Id",
+	DefaultSort:  "id",
+	DefaultOrder: "ASC",
+	Cursor:       true,
+}
+
+// searchDefaults ranks searches by relevance unless the client chose another sort
+func searchDefaults(query map[string]string) map[string]string {
+	if strings.TrimSpace(query["search"]) == "" || strings.TrimSpace(query["sort_by"]) != "" {
+		return query
+	}
+	withDefaults := map[string]string{"sort_by": "relevance", "order": "DESC"}
+	for key, value := range query {
+		withDefaults[key] = value
+	}
+	return withDefaults
+}
+
+// sortValue is the value of the field p is sorted on, as stored in a cursor
+func sortValue(p models.Product, sortBy string) interface{} {
+	switch sortBy {
+	case "title":
+		return p.Title
+	case "description":
+		return p.Description
+	case "price":
+		return p.Price
+	case "category_id":
+		return p.CategId
+	case "stock":
+		return p.Stock
+	case "created_at":
+		return p.CreatedAt
+	default:
+		return p.Id
+	}
+}
@@ -0,0 +1,23 @@
+package product
+
+import (
+	"testing"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/ddessilvestri/ecommerce-go/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test a cursor on a nullable column compares against the coalesced value, so NULL rows are not skipped
+func TestQuerySpecCursorOnNullableColumn(t *testing.T) {
+	cursor := tools.EncodeCursor(models.Cursor{SortBy: "stock", Order: "ASC", Value: 0, Id: 7})
+	q, err := QuerySpec.Parse(map[string]string{"sort_by": "stock", "cursor": cursor})
+	require.NoError(t, err)
+
+	query, _, err := q.ApplyTo(squirrel.Select("*").From("products")).ToSql()
+	require.NoError(t, err)
+	assert.Contains(t, query, "(COALESCE(Prod_Stock, 0) > ? OR (COALESCE(Prod_Stock, 0) = ? AND Prod_Id > ?))")
+	assert.Contains(t, query, "ORDER BY COALESCE(Prod_Stock, 0) ASC, Prod_Id ASC")
+}
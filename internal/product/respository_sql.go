@@ -2,10 +2,10 @@ package product
 
 import (
 	"database/sql"
-	"fmt"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/ddessilvestri/ecommerce-go/tools"
 )
 
 // This struct acts like a "class" in Go.
@@ -45,13 +45,17 @@ func (r *repositorySQL) Insert(p models.Product) (int64, error) {
 		values = append(values, p.Stock)
 	}
 	if p.CategId != 0 {
-		columns = append(columns, "Prod_CategId")
+		columns = append(columns, "Prod_CategoryId")
 		values = append(values, p.CategId)
 	}
 	if p.Path != "" {
 		columns = append(columns, "Prod_Path")
 		values = append(values, p.Path)
 	}
+	if p.Status != "" {
+		columns = append(columns, "Prod_Status")
+		values = append(values, p.Status)
+	}
 
 	query, args, err := squirrel.
 		Insert("products").
@@ -73,34 +77,71 @@ func (r *repositorySQL) Insert(p models.Product) (int64, error) {
 
 }
 
+// Update replaces every editable column, so zero values and empty strings are stored as sent.
+// The status is lifecycle rather than content: it only changes when sent.
 func (r *repositorySQL) Update(p models.Product) error {
 	builder := squirrel.
 		Update("products").
 		PlaceholderFormat(squirrel.Question).
-		Set("Prod_Updated", squirrel.Expr("NOW()"))
+		Set("Prod_Updated", squirrel.Expr("NOW()")).
+		Set("Prod_Title", p.Title).
+		Set("Prod_Description", p.Description).
+		Set("Prod_Price", p.Price).
+		Set("Prod_Stock", p.Stock).
+		Set("Prod_CategoryId", p.CategId).
+		Set("Prod_Path", p.Path).
+		Where(squirrel.Eq{"Prod_Id": p.Id})
+	if p.Status != "" {
+		builder = builder.Set("Prod_Status", p.Status)
+	}
 
-	// Agregamos los campos modificados
-	if p.Title != "" {
-		builder = builder.Set("Prod_Title", p.Title)
+	query, args, err := builder.ToSql()
+
+	if err != nil {
+		return err
 	}
-	if p.Description != "" {
-		builder = builder.Set("Prod_Description", p.Description)
+
+	_, err = r.db.Exec(query, args...)
+
+	if err != nil {
+		return err
 	}
-	if p.Price != 0 {
-		builder = builder.Set("Prod_Price", p.Price)
+
+	return nil
+}
+
+// Patch only touches the fields present in the patch; null fields are set to NULL
+func (r *repositorySQL) Patch(id int, p models.ProductPatch) error {
+	columns := map[string]interface{}{
+		"Prod_Updated": squirrel.Expr("NOW()"),
 	}
-	if p.Stock != 0 {
-		builder = builder.Set("Prod_Stock", p.Stock)
+	if p.Title.Set {
+		columns["Prod_Title"] = p.Title.SQLValue()
 	}
-	if p.CategId != 0 {
-		builder = builder.Set("Prod_CategId", p.CategId)
+	if p.Description.Set {
+		columns["Prod_Description"] = p.Description.SQLValue()
 	}
-	if p.Path != "" {
-		builder = builder.Set("Prod_Path", p.Path)
+	if p.Price.Set {
+		columns["Prod_Price"] = p.Price.SQLValue()
+	}
+	if p.Stock.Set {
+		columns["Prod_Stock"] = p.Stock.SQLValue()
+	}
+	if p.CategId.Set {
+		columns["Prod_CategoryId"] = p.CategId.SQLValue()
+	}
+	if p.Path.Set {
+		columns["Prod_Path"] = p.Path.SQLValue()
+	}
+	if p.Status.Set {
+		columns["Prod_Status"] = p.Status.SQLValue()
 	}
 
-	query, args, err := builder.
-		Where(squirrel.Eq{"Prod_Id": p.Id}).
+	query, args, err := squirrel.
+		Update("products").
+		PlaceholderFormat(squirrel.Question).
+		SetMap(columns).
+		Where(squirrel.Eq{"Prod_Id": id}).
 		ToSql()
 
 	if err != nil {
@@ -108,17 +149,41 @@ func (r *repositorySQL) Update(p models.Product) error {
 	}
 
 	_, err = r.db.Exec(query, args...)
+	return err
+}
+
+func (r *repositorySQL) Exists(id int) bool {
+	query, args, err := squirrel.
+		Select("1").
+		From("products").
+		Where(squirrel.Eq{"Prod_Id": id}).
+		Limit(1).
+		ToSql()
 
 	if err != nil {
-		return err
+		return false
 	}
 
-	return nil
+	var exists int
+	err = r.db.QueryRow(query, args...).Scan(&exists)
+
+	return err == nil
 }
 
-func (r *repositorySQL) Delete(id int) error {
+// CategoryExists reports whether a product can be filed under the category
+func (r *repositorySQL) CategoryExists(id int) bool {
+	var exists int
+	err := r.db.QueryRow("SELECT 1 FROM category WHERE Categ_Id = ? LIMIT 1", id).Scan(&exists)
+	return err == nil
+}
+
+// SetStatus moves a product through its lifecycle; rows are never deleted, since
+// orders_detail keeps pointing at them
+func (r *repositorySQL) SetStatus(id int, status string) error {
 	query, args, err := squirrel.
-		Delete("products").
+		Update("products").
+		Set("Prod_Status", status).
+		Set("Prod_Updated", squirrel.Expr("NOW()")).
 		Where(squirrel.Eq{"Prod_Id": id}).
 		ToSql()
 
@@ -127,21 +192,15 @@ func (r *repositorySQL) Delete(id int) error {
 	}
 
 	_, err = r.db.Exec(query, args...)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return err
 }
 
 func (r *repositorySQL) GetById(id int) (models.Product, error) {
 	query, args, err := squirrel.
-		Select("p.Prod_Id", "p.Prod_Title", "p.Prod_Description",
-			"p.Prod_CreatedAt", "p.Prod_Updated", "p.Prod_Price", "p.Prod_Path",
-			"p.Prod_CategoryId", "p.Prod_Stock", "c.Categ_Path").
-		From("products p").
-		Join("category c ON p.Prod_CategoryId = c.Categ_Id").
-		Where(squirrel.Eq{"p.Prod_Id": id}).
+		Select(productColumns...).
+		From("products").
+		LeftJoin("category ON Prod_CategoryId = Categ_Id").
+		Where(squirrel.Eq{"Prod_Id": id}).
 		PlaceholderFormat(squirrel.Question).
 		ToSql()
 
@@ -149,9 +208,7 @@ func (r *repositorySQL) GetById(id int) (models.Product, error) {
 		return models.Product{}, err
 	}
 
-	row := r.db.QueryRow(query, args...)
-	var p models.Product
-	err = row.Scan(&p.Id, &p.Title, &p.Description, &p.CreatedAt, &p.Updated, &p.Price, &p.Path, &p.CategId, &p.Stock, &p.CategPath)
+	p, err := scanProduct(r.db.QueryRow(query, args...))
 	if err != nil {
 		return models.Product{}, err
 	}
@@ -161,11 +218,9 @@ func (r *repositorySQL) GetById(id int) (models.Product, error) {
 
 func (r *repositorySQL) GetBySlug(slug string) (models.Product, error) {
 	query, args, err := squirrel.
-		Select("Prod_Id", "Prod_Title", "Prod_Description",
-			"Prod_CreatedAt", "Prod_Updated", "Prod_Price", "Prod_Path",
-			"Prod_CategoryId", "Prod_Stock", "Categ_Path").
+		Select(productColumns...).
 		From("products").
-		Join("category ON products.Prod_CategoryId = Categ_Id").
+		LeftJoin("category ON Prod_CategoryId = Categ_Id").
 		Where(squirrel.Eq{"Prod_Path": slug}).
 		PlaceholderFormat(squirrel.Question).
 		ToSql()
@@ -174,10 +229,7 @@ func (r *repositorySQL) GetBySlug(slug string) (models.Product, error) {
 		return models.Product{}, err
 	}
 
-	row := r.db.QueryRow(query, args...)
-	var p models.Product
-	err = row.Scan(&p.Id, &p.Title, &p.Description, &p.CreatedAt, &p.Updated, &p.Price, &p.Path, &p.CategId, &p.Stock, &p.CategPath)
-
+	p, err := scanProduct(r.db.QueryRow(query, args...))
 	if err != nil {
 		return models.Product{}, err
 	}
@@ -185,22 +237,9 @@ func (r *repositorySQL) GetBySlug(slug string) (models.Product, error) {
 	return p, nil
 }
 
-func (r *repositorySQL) GetByCategoryId(id int) ([]models.Product, error) {
-	query, args, err := squirrel.
-		Select("Prod_Id", "Prod_Title", "Prod_Description",
-			"Prod_CreatedAt", "Prod_Updated", "Prod_Price", "Prod_Path",
-			"Prod_CategoryId", "Prod_Stock", "Categ_Path").
-		From("products").
-		Join("category ON products.Prod_CategoryId = Categ_Id").
-		Where(squirrel.Eq{"Prod_CategId": id}).
-		PlaceholderFormat(squirrel.Question).
-		ToSql()
-
-	if err != nil {
-		return nil, err
-	}
-
-	rows, err := r.db.Query(query, args...)
+// ListForIndex returns the fields the in-memory search index is built from, for every product
+func (r *repositorySQL) ListForIndex() ([]models.Product, error) {
+	rows, err := r.db.Query("SELECT Prod_Id, Prod_Title, COALESCE(Prod_Description, ''), Prod_Status FROM products")
 	if err != nil {
 		return nil, err
 	}
@@ -209,79 +248,75 @@ func (r *repositorySQL) GetByCategoryId(id int) ([]models.Product, error) {
 	var products []models.Product
 	for rows.Next() {
 		var p models.Product
-		if err = rows.Scan(&p.Id, &p.Title, &p.Description, &p.CreatedAt, &p.Updated, &p.Price, &p.Path, &p.CategId, &p.Stock, &p.CategPath); err != nil {
+		if err := rows.Scan(&p.Id, &p.Title, &p.Description, &p.Status); err != nil {
 			return nil, err
 		}
 		products = append(products, p)
 	}
-
-	return products, nil
+	return products, rows.Err()
 }
 
-func (r *repositorySQL) GetByCategorySlug(slug string) ([]models.Product, error) {
+// TitlesWithPrefix returns up to limit distinct titles starting with prefix, shortest first
+func (r *repositorySQL) TitlesWithPrefix(prefix string, limit int) ([]string, error) {
 	query, args, err := squirrel.
-		Select("Prod_Id", "Prod_Title", "Prod_Description",
-			"Prod_CreatedAt", "Prod_Updated", "Prod_Price", "Prod_Path",
-			"Prod_CategoryId", "Prod_Stock", "Categ_Path").
+		Select("DISTINCT Prod_Title").
 		From("products").
-		Join("category ON products.Prod_CategoryId = Categ_Id").
-		Where(squirrel.Eq{"Categ_Path": slug}).
+		Where(squirrel.Like{"Prod_Title": escapeLike(prefix) + "%"}).
+		Where(squirrel.Eq{"Prod_Status": models.ProductActive}).
+		OrderBy("CHAR_LENGTH(Prod_Title)", "Prod_Title").
+		Limit(uint64(limit)).
 		PlaceholderFormat(squirrel.Question).
 		ToSql()
-
 	if err != nil {
 		return nil, err
 	}
+
 	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var products []models.Product
+	var titles []string
 	for rows.Next() {
-		var p models.Product
-		if err = rows.Scan(&p.Id, &p.Title, &p.Description, &p.CreatedAt, &p.Updated, &p.Price, &p.Path, &p.CategId, &p.Stock, &p.CategPath); err != nil {
+		var title string
+		if err := rows.Scan(&title); err != nil {
 			return nil, err
 		}
-		products = append(products, p)
+		titles = append(titles, title)
 	}
-
-	return products, nil
-
+	return titles, rows.Err()
 }
 
-func (r *repositorySQL) SearchByText(search string, offset, limit int, sortBy, order string) ([]models.Product, error) {
-	allowedSorts := map[string]string{
-		"id":          "Prod_Id",
-		"title":       "Prod_Title",
-		"description": "Prod_Description",
-		"price":       "Prod_Price",
-		"category_id": "Prod_CategId",
-		"stock":       "Prod_Stock",
-		"created_at":  "Prod_CreatedAt",
+// ListCategories returns every category, by name, for suggestions
+func (r *repositorySQL) ListCategories() ([]models.Category, error) {
+	rows, err := r.db.Query("SELECT Categ_Id, Categ_Name, Categ_Path FROM category ORDER BY Categ_Name")
+	if err != nil {
+		return nil, err
 	}
-	dbSortBy, ok := allowedSorts[sortBy]
-	if !ok {
-		dbSortBy = "Prod_Title"
+	defer rows.Close()
+
+	var categories []models.Category
+	for rows.Next() {
+		var c models.Category
+		if err := rows.Scan(&c.CategID, &c.CategName, &c.CategPath); err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
 	}
+	return categories, rows.Err()
+}
 
-	queryBuilder := squirrel.
-		Select("Prod_Id", "Prod_Title", "Prod_Description",
-			"Prod_CreatedAt", "Prod_Updated", "Prod_Price", "Prod_Path",
-			"Prod_CategoryId", "Prod_Stock", "Categ_Path").
-		From("products").
-		Join("category ON products.Prod_CategoryId = Categ_Id").
-		Where(squirrel.Or{
-			squirrel.Like{"Prod_Title": "%" + search + "%"},
-			squirrel.Like{"Prod_Description": "%" + search + "%"},
-		}).
-		OrderBy(fmt.Sprintf("%s %s", dbSortBy, order)).
-		Offset(uint64(offset)).
+// PopularSearches returns the limit most run searches run at least minHits times, most run first
+func (r *repositorySQL) PopularSearches(limit, minHits int) ([]string, error) {
+	query, args, err := squirrel.
+		Select("SQ_Query").
+		From("search_queries").
+		Where(squirrel.GtOrEq{"SQ_Hits": minHits}).
+		OrderBy("SQ_Hits DESC", "SQ_LastSearched DESC").
 		Limit(uint64(limit)).
-		PlaceholderFormat(squirrel.Question)
-
-	query, args, err := queryBuilder.ToSql()
+		PlaceholderFormat(squirrel.Question).
+		ToSql()
 	if err != nil {
 		return nil, err
 	}
@@ -292,45 +327,47 @@ func (r *repositorySQL) SearchByText(search string, offset, limit int, sortBy, o
 	}
 	defer rows.Close()
 
-	var products []models.Product
+	var queries []string
 	for rows.Next() {
-		var p models.Product
-		if err = rows.Scan(&p.Id, &p.Title, &p.Description, &p.CreatedAt, &p.Updated, &p.Price, &p.Path, &p.CategId, &p.Stock, &p.CategPath); err != nil {
+		var q string
+		if err := rows.Scan(&q); err != nil {
 			return nil, err
 		}
-		products = append(products, p)
+		queries = append(queries, q)
 	}
-
-	return products, nil
+	return queries, rows.Err()
 }
 
-func (r *repositorySQL) GetAll(offset, limit int, sortBy, order string) ([]models.Product, error) {
-	allowedSorts := map[string]string{
-		"id":          "Prod_Id",
-		"title":       "Prod_Title",
-		"description": "Prod_Description",
-		"price":       "Prod_Price",
-		"category_id": "Prod_CategId",
-		"stock":       "Prod_Stock",
-		"created_at":  "Prod_CreatedAt",
-	}
-	dbSortBy, ok := allowedSorts[sortBy]
-	if !ok {
-		dbSortBy = "Prod_Title"
+// RecordSearch counts one more run of a normalized search
+func (r *repositorySQL) RecordSearch(search string) error {
+	query, args, err := squirrel.
+		Insert("search_queries").
+		Columns("SQ_Query").
+		Values(search).
+		Suffix("ON DUPLICATE KEY UPDATE SQ_Hits = SQ_Hits + 1, SQ_LastSearched = NOW()").
+		PlaceholderFormat(squirrel.Question).
+		ToSql()
+	if err != nil {
+		return err
 	}
 
+	_, err = r.db.Exec(query, args...)
+	return err
+}
+
+// Find returns the page of products described by q among those matching every clause of f, in a single query
+func (r *repositorySQL) Find(f Filter, q tools.Query) ([]models.Product, error) {
 	queryBuilder := squirrel.
-		Select("Prod_Id", "Prod_Title", "Prod_Description",
-			"Prod_CreatedAt", "Prod_Updated", "Prod_Price", "Prod_Path",
-			"Prod_CategoryId", "Prod_Stock", "Categ_Path").
+		Select(productColumns...).
+		Column(squirrel.Alias(f.Relevance(), "relevance")).
 		From("products").
-		Join("category ON products.Prod_CategoryId = Categ_Id").
-		OrderBy(fmt.Sprintf("%s %s", dbSortBy, order)).
-		Offset(uint64(offset)).
-		Limit(uint64(limit)).
+		LeftJoin("category ON Prod_CategoryId = Categ_Id").
 		PlaceholderFormat(squirrel.Question)
+	if where := f.Where(); len(where) > 0 {
+		queryBuilder = queryBuilder.Where(where)
+	}
 
-	query, args, err := queryBuilder.ToSql()
+	query, args, err := q.ApplyTo(queryBuilder).ToSql()
 	if err != nil {
 		return nil, err
 	}
@@ -343,11 +380,81 @@ func (r *repositorySQL) GetAll(offset, limit int, sortBy, order string) ([]model
 
 	var products []models.Product
 	for rows.Next() {
-		var p models.Product
-		if err = rows.Scan(&p.Id, &p.Title, &p.Description, &p.CreatedAt, &p.Updated, &p.Price, &p.Path, &p.CategId, &p.Stock, &p.CategPath); err != nil {
+		var score sql.NullFloat64
+		p, err := scanProduct(rows, &score)
+		if err != nil {
 			return nil, err
 		}
+		p.Score = score.Float64
 		products = append(products, p)
 	}
-	return products, nil
+	return products, rows.Err()
+}
+
+// Count returns how many products match f and the filters of q, with the same join as Find
+func (r *repositorySQL) Count(f Filter, q tools.Query) (int, error) {
+	queryBuilder := q.ApplyFiltersTo(squirrel.
+		Select("COUNT(*)").
+		From("products").
+		LeftJoin("category ON Prod_CategoryId = Categ_Id").
+		PlaceholderFormat(squirrel.Question))
+	if where := f.Where(); len(where) > 0 {
+		queryBuilder = queryBuilder.Where(where)
+	}
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	var total int
+	err = r.db.QueryRow(query, args...).Scan(&total)
+	return total, err
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// productColumns are selected by every product query in this file, in the order scanProduct expects.
+// Queries LEFT JOIN category, so products without one (e.g. archived with their category) still resolve.
+var productColumns = []string{"Prod_Id", "Prod_Title", "Prod_Description",
+	createdAtColumn, "Prod_Updated", "Prod_Price", "Prod_Path",
+	"Prod_CategoryId", stockColumn, "Prod_Status", "Categ_Path"}
+
+// scanProduct reads productColumns.
+// Description, path, update date, category and its path are nullable, so they go through sql.Null*.
+// extra receives any column selected after Categ_Path.
+func scanProduct(row rowScanner, extra ...interface{}) (models.Product, error) {
+	var p models.Product
+	var description, path, updated, categPath sql.NullString
+	var categId sql.NullInt64
+	dest := []interface{}{&p.Id, &p.Title, &description, &p.CreatedAt, &updated, &p.Price, &path, &categId, &p.Stock, &p.Status, &categPath}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return models.Product{}, err
+	}
+	p.Description = description.String
+	p.Path = path.String
+	p.Updated = updated.String
+	p.CategId = int(categId.Int64)
+	p.CategPath = categPath.String
+	return p, nil
+}
+
+// DeleteSearch forgets a normalized search and its count
+func (r *repositorySQL) DeleteSearch(search string) error {
+	res, err := r.db.Exec("DELETE FROM search_queries WHERE SQ_Query = ?", search)
+	if err != nil {
+		return err
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrSearchNotFound
+	}
+	return nil
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/ddessilvestri/ecommerce-go/routers/route"
 )
 
 type Router struct {
@@ -13,11 +14,25 @@ type Router struct {
 
 func NewRouter(db *sql.DB) *Router {
 	repo := NewSQLRepository(db)
-	service := NewService(repo)
+	service := NewService(repo, newSearchBackend(repo))
 	handler := NewHandler(service)
 	return &Router{handler: handler}
 }
 
+// RegisterRoutes declares the product endpoints on the route table
+func RegisterRoutes(table *route.Table, db *sql.DB) {
+	r := NewRouter(db)
+	table.Handle(route.GET, "/product", r.Get).AllowAnonymous()
+	table.Handle(route.GET, "/product/suggest", r.Suggest).AllowAnonymous()
+	table.Handle(route.POST, "/product", r.Post).Require(models.RoleAdmin)
+	table.Handle(route.PUT, "/product/{id}", r.Put).Require(models.RoleAdmin)
+	table.Handle(route.PATCH, "/product/{id}", r.Patch).Require(models.RoleAdmin)
+	table.Handle(route.DELETE, "/product/{id}", r.Delete).Require(models.RoleAdmin)
+	table.Handle(route.POST, "/product/{id}/restore", r.Restore).Require(models.RoleAdmin)
+	table.Handle(route.GET, "/admin/products", r.AdminGet).Require(models.RoleAdmin)
+	table.Handle(route.DELETE, "/admin/search-queries", r.PurgeSearch).Require(models.RoleAdmin)
+}
+
 func (r *Router) Post(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
 	return r.handler.Post(requestWithContext)
 }
@@ -26,10 +41,30 @@ func (r *Router) Get(requestWithContext models.RequestWithContext) *events.APIGa
 	return r.handler.Get(requestWithContext)
 }
 
+func (r *Router) AdminGet(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	return r.handler.AdminGet(requestWithContext)
+}
+
+func (r *Router) Suggest(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	return r.handler.Suggest(requestWithContext)
+}
+
+func (r *Router) PurgeSearch(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	return r.handler.PurgeSearch(requestWithContext)
+}
+
 func (r *Router) Put(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
 	return r.handler.Put(requestWithContext)
 }
 
+func (r *Router) Patch(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	return r.handler.Patch(requestWithContext)
+}
+
 func (r *Router) Delete(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
 	return r.handler.Delete(requestWithContext)
 }
+
+func (r *Router) Restore(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	return r.handler.Restore(requestWithContext)
+}
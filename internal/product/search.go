@@ -0,0 +1,104 @@
+package product
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/ddessilvestri/ecommerce-go/models"
+)
+
+// Search backends selectable with SEARCH_BACKEND
+const (
+	SearchBackendSQL    = "sql"
+	SearchBackendMemory = "memory"
+)
+
+// SearchMatch is a search translated to SQL, so the other filters, the sort and the paging
+// still run in the single product query
+type SearchMatch struct {
+	Where     squirrel.Sqlizer // rows matching the search
+	Relevance squirrel.Sqlizer // score of a row, selected as "relevance"
+	Terms     []string         // indexed words that matched, for snippets; empty means the words of the search
+	Capped    bool             // only the best hits were kept, so counts stop at the backend's limit
+}
+
+// SearchBackend finds the products matching a search text
+type SearchBackend interface {
+	// Match translates a search, in SearchNatural or SearchBoolean mode, into SQL
+	Match(search, mode string) (SearchMatch, error)
+	// Complete returns up to limit product titles for a partially typed search
+	Complete(prefix string, limit int) ([]string, error)
+	// Upsert and Remove keep the backend in step with product writes
+	Upsert(p models.Product)
+	Remove(id int)
+}
+
+// sqlSearch delegates to the FULLTEXT index of the products table
+type sqlSearch struct {
+	repo Storage
+}
+
+func (s sqlSearch) Match(search, mode string) (SearchMatch, error) {
+	match := fulltextMatch(search, mode)
+	return SearchMatch{Where: match, Relevance: match}, nil
+}
+
+func (s sqlSearch) Complete(prefix string, limit int) ([]string, error) {
+	return s.repo.TitlesWithPrefix(strings.TrimSpace(prefix), limit)
+}
+
+// The table is the index, there is nothing to refresh
+func (s sqlSearch) Upsert(p models.Product) {}
+func (s sqlSearch) Remove(id int)           {}
+
+// fulltextMatch is the MATCH ... AGAINST score of a search; in a WHERE clause it keeps the rows scoring above zero.
+// It uses the FULLTEXT index added by migrations/001_products_fulltext.sql.
+func fulltextMatch(search, mode string) squirrel.Sqlizer {
+	modifier := "NATURAL LANGUAGE MODE"
+	if mode == SearchBoolean {
+		modifier = "BOOLEAN MODE"
+	}
+	return squirrel.Expr("MATCH(Prod_Title, Prod_Description) AGAINST (? IN "+modifier+")", search)
+}
+
+var (
+	searchMu      sync.Mutex
+	searchBackend = SearchBackendSQL
+	searchTTL     = 5 * time.Minute
+	memoryIndex   *MemoryIndex
+)
+
+// ConfigureSearch selects the search backend for the process.
+// ttl bounds how stale the in-memory index may get: writes served by other instances are only seen on rebuild.
+func ConfigureSearch(backend string, ttl time.Duration) error {
+	if backend != SearchBackendSQL && backend != SearchBackendMemory {
+		return fmt.Errorf("unknown search backend %q: use %q or %q", backend, SearchBackendSQL, SearchBackendMemory)
+	}
+
+	searchMu.Lock()
+	defer searchMu.Unlock()
+	searchBackend = backend
+	searchTTL = ttl
+	memoryIndex = nil
+	return nil
+}
+
+// newSearchBackend returns the configured backend. Routes are rebuilt whenever the pool is replaced,
+// so the in-memory index is shared by the process and only its product source is swapped.
+func newSearchBackend(repo Storage) SearchBackend {
+	searchMu.Lock()
+	defer searchMu.Unlock()
+
+	if searchBackend != SearchBackendMemory {
+		return sqlSearch{repo: repo}
+	}
+	if memoryIndex == nil {
+		memoryIndex = NewMemoryIndex(repo.ListForIndex, searchTTL)
+	} else {
+		memoryIndex.SetSource(repo.ListForIndex)
+	}
+	return memoryIndex
+}
@@ -1,26 +1,41 @@
 package product
 
 import (
+	"database/sql"
 	"errors"
+	"fmt"
 
 	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/ddessilvestri/ecommerce-go/tools"
 )
 
 // Service provides methods for business logic related to category.
 type Service struct {
-	repo Storage // This is the interface, so it's decoupled from repositorySQL
+	repo   Storage       // This is the interface, so it's decoupled from repositorySQL
+	search SearchBackend // Full-text matching, see ConfigureSearch
 }
 
-func NewService(repo Storage) *Service {
-	return &Service{repo: repo}
+func NewService(repo Storage, search SearchBackend) *Service {
+	return &Service{repo: repo, search: search}
 }
 
 func (s *Service) Create(c models.Product) (int64, error) {
 	if c.Title == "" {
 		return 0, ErrInvalidProduct
 	}
+	if !editableStatus(c.Status) {
+		return 0, ErrInvalidProductStatus
+	}
+	if c.CategId != 0 && !s.repo.CategoryExists(c.CategId) {
+		return 0, ErrCategoryNotFound
+	}
 
-	return s.repo.Insert(c)
+	id, err := s.repo.Insert(c)
+	if err != nil {
+		return 0, err
+	}
+	s.reindex(int(id))
+	return id, nil
 }
 
 func (s *Service) Update(c models.Product) error {
@@ -30,16 +45,104 @@ func (s *Service) Update(c models.Product) error {
 	if c.Id < 1 {
 		return ErrInvalidProductId
 	}
-	return s.repo.Update(c)
+	if !editableStatus(c.Status) {
+		return ErrInvalidProductStatus
+	}
+	if err := s.checkStatusChange(c.Id, c.Status); err != nil {
+		return err
+	}
+	// PUT writes every column, so the category must be sent again
+	if c.CategId < 1 {
+		return ErrMissingCategory
+	}
+	if !s.repo.CategoryExists(c.CategId) {
+		return ErrCategoryNotFound
+	}
+	if err := s.repo.Update(c); err != nil {
+		return err
+	}
+	s.reindex(c.Id)
+	return nil
+
+}
 
+// Patch applies a JSON Merge Patch; required columns can be changed but not cleared
+func (s *Service) Patch(id int, p models.ProductPatch) error {
+	if id < 1 {
+		return ErrInvalidProductId
+	}
+	if p.Title.Set && (p.Title.Null || p.Title.Value == "") {
+		return ErrInvalidProduct
+	}
+	if p.Price.Null {
+		return ErrNullPrice
+	}
+	if p.Stock.Null {
+		return ErrNullStock
+	}
+	if p.CategId.Null {
+		return ErrNullCategory
+	}
+	if p.CategId.Set && !s.repo.CategoryExists(p.CategId.Value) {
+		return ErrCategoryNotFound
+	}
+	if p.Status.Set && (p.Status.Null || !editableStatus(p.Status.Value)) {
+		return ErrInvalidProductStatus
+	}
+	if !s.repo.Exists(id) {
+		return ErrProductNotFound
+	}
+	if p.Status.Set {
+		if err := s.checkStatusChange(id, p.Status.Value); err != nil {
+			return err
+		}
+	}
+	if err := s.repo.Patch(id, p); err != nil {
+		return err
+	}
+	s.reindex(id)
+	return nil
 }
 
+// Delete archives the product: orders keep referencing it, so the row stays and only leaves the listings
 func (s *Service) Delete(id int) error {
 	if id < 1 {
 		return ErrInvalidProductId
 	}
-	return s.repo.Delete(id)
+	if !s.repo.Exists(id) {
+		return ErrProductNotFound
+	}
+	if err := s.repo.SetStatus(id, models.ProductArchived); err != nil {
+		return err
+	}
+	s.reindex(id)
+	return nil
+}
 
+// Restore puts an archived product back on sale
+func (s *Service) Restore(id int) error {
+	if id < 1 {
+		return ErrInvalidProductId
+	}
+	p, err := s.repo.GetById(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrProductNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if p.Status != models.ProductArchived {
+		return ErrProductNotArchived
+	}
+	// Deleting its category with archiveProducts leaves the product without one
+	if p.CategId == 0 {
+		return ErrRestoreWithoutCategory
+	}
+	if err := s.repo.SetStatus(id, models.ProductActive); err != nil {
+		return err
+	}
+	s.reindex(id)
+	return nil
 }
 
 func (s *Service) GetById(id int) (models.Product, error) {
@@ -52,19 +155,49 @@ func (s *Service) GetById(id int) (models.Product, error) {
 
 }
 
-func (s *Service) GetByCategoryId(id int) ([]models.Product, error) {
+// Find lists the products matching every clause of f, paginated by page or cursor
+func (s *Service) Find(f Filter, q tools.Query) (models.Page[models.Product], error) {
+	if q.SortBy == "relevance" && f.Search == "" {
+		return models.Page[models.Product]{}, ErrRelevanceWithoutSearch
+	}
+	if q.SortBy == "relevance" && q.After != nil {
+		return models.Page[models.Product]{}, ErrRelevanceCursor
+	}
 
-	if id < 1 {
-		return []models.Product{}, ErrInvalidProductId
+	if f.Search != "" {
+		match, err := s.search.Match(f.Search, f.SearchMode)
+		if err != nil {
+			return models.Page[models.Product]{}, err
+		}
+		f.match = &match
 	}
 
-	return s.repo.GetByCategoryId(id)
+	products, err := s.repo.Find(f, q.Fetch())
+	if err != nil {
+		return models.Page[models.Product]{}, err
+	}
+	total, err := s.repo.Count(f, q)
+	if err != nil {
+		return models.Page[models.Product]{}, err
+	}
 
-}
+	// Only the first page of a plain search that found something counts towards popular queries
+	if f.Search != "" && f.SearchMode == SearchNatural && q.Page == 1 && q.After == nil && total > 0 {
+		s.recordSearch(f.Search)
+	}
 
-func (s *Service) GetAll(page, limit int, sortBy, order string) ([]models.Product, error) {
-	offset := (page - 1) * limit
-	return s.repo.GetAll(offset, limit, sortBy, order)
+	if f.Search != "" {
+		terms := f.match.Terms
+		if len(terms) == 0 {
+			terms = searchTerms(f.Search)
+		}
+		for i := range products {
+			products[i].Snippets = snippets(products[i], terms)
+		}
+	}
+	page := newPage(products, q, total)
+	page.Capped = f.match != nil && f.match.Capped
+	return page, nil
 }
 
 func (s *Service) GetBySlug(slug string) (models.Product, error) {
@@ -77,21 +210,71 @@ func (s *Service) GetBySlug(slug string) (models.Product, error) {
 
 }
 
-func (s *Service) GetByCategorySlug(slug string) ([]models.Product, error) {
+// editableStatus reports whether a write may set the status; "" keeps the current one.
+// Archiving and restoring go through Delete and Restore.
+func editableStatus(status string) bool {
+	return status == "" || status == models.ProductDraft || status == models.ProductActive
+}
 
-	if slug == "" {
-		return []models.Product{}, ErrInvalidProductSlug
+// checkStatusChange keeps archived products archived when a write sends a status:
+// Restore is the only way out, since it checks the product still has a category
+func (s *Service) checkStatusChange(id int, status string) error {
+	if status == "" {
+		return nil
 	}
+	current, err := s.repo.GetById(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrProductNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if current.Status == models.ProductArchived {
+		return ErrArchivedStatusChange
+	}
+	return nil
+}
 
-	return s.repo.GetByCategorySlug(slug)
-
+// reindex hands the stored product to the search backend; the write already succeeded,
+// so a failure only leaves the index stale until its next rebuild
+func (s *Service) reindex(id int) {
+	p, err := s.repo.GetById(id)
+	if err != nil {
+		fmt.Println("Search index update failed for product", id, ":", err.Error())
+		return
+	}
+	s.search.Upsert(p)
 }
 
-func (s *Service) SearchByText(text string, page, limit int, sortBy, order string) ([]models.Product, error) {
-	offset := (page - 1) * limit
-	return s.repo.SearchByText(text, offset, limit, sortBy, order)
+// newPage wraps products in the page envelope, with cursors keyed on the sort field and Prod_Id.
+// Relevance is computed per query, so relevance-sorted pages only page by number.
+func newPage(products []models.Product, q tools.Query, total int) models.Page[models.Product] {
+	if q.SortBy == "relevance" {
+		return tools.NewListPage(products, q, total, nil)
+	}
+	return tools.NewListPage(products, q, total, func(p models.Product) (interface{}, int) {
+		return sortValue(p, q.SortBy), p.Id
+	})
 }
 
-var ErrInvalidProduct = errors.New("invalid product: title is required")
-var ErrInvalidProductId = errors.New("invalid product Id: Id < 1 ")
-var ErrInvalidProductSlug = errors.New("invalid product Slug: empty slug ")
+var ErrInvalidProduct = models.NewFieldError("prodTitle", "invalid product: title is required")
+var ErrInvalidProductId = models.NewFieldError("id", "invalid product Id: Id < 1")
+var ErrInvalidProductSlug = models.NewFieldError("slug", "invalid product Slug: empty slug")
+var ErrNullPrice = models.NewFieldError("prodPrice", "invalid product: price cannot be null")
+var ErrNullStock = models.NewFieldError("prodStock", "invalid product: stock cannot be null")
+var ErrNullCategory = models.NewFieldError("prodCategId", "invalid product: category cannot be null")
+var ErrMissingCategory = models.NewFieldError("prodCategId", "invalid product: category is required")
+var ErrCategoryNotFound = models.NewFieldError("prodCategId", "invalid product: category not found")
+var ErrProductNotFound = models.NewNotFoundError("product not found")
+var ErrAmbiguousCategory = models.NewFieldError("categId", "invalid filter: use either 'categId' or 'slugCateg', not both")
+var ErrRelevanceWithoutSearch = models.NewFieldError("sort_by", "invalid 'sort_by' parameter: 'relevance' needs 'search'")
+var ErrRelevanceCursor = models.NewFieldError("cursor", "'cursor' is not supported when sorting by relevance, use 'page'")
+var ErrEmptySuggestPrefix = models.NewFieldError("q", "invalid 'q' parameter: type at least one character")
+var ErrSuggestPrefixTooLong = models.NewFieldError("q", "invalid 'q' parameter: too long")
+var ErrEmptyPurgeQuery = models.NewFieldError("q", "invalid 'q' parameter: give the search to purge")
+var ErrSearchNotFound = models.NewNotFoundError("search not found")
+var ErrInvalidPriceRange = models.NewFieldError("min_price", "invalid filter: 'min_price' is greater than 'max_price'")
+var ErrInvalidProductStatus = models.NewFieldError("prodStatus", "invalid product status: use 'draft' or 'active', archive with DELETE")
+var ErrArchivedStatusChange = models.NewConflictError("product is archived: use POST /product/{id}/restore to put it back on sale")
+var ErrProductNotArchived = models.NewConflictError("product is not archived")
+var ErrRestoreWithoutCategory = models.NewConflictError("product has no category: set prodCategId before restoring it")
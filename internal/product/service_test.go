@@ -0,0 +1,102 @@
+package product
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lifecycleStore keeps products in memory for status changes; other Storage methods are not used
+type lifecycleStore struct {
+	Storage
+	products map[int]models.Product
+}
+
+func newLifecycleService(t *testing.T) (*Service, *lifecycleStore) {
+	store := &lifecycleStore{products: map[int]models.Product{
+		1: {Id: 1, Title: "Wireless Mouse", CategId: 2, Status: models.ProductActive},
+		2: {Id: 2, Title: "Mouse Pad XL", Status: models.ProductArchived},
+		3: {Id: 3, Title: "Trackball", CategId: 2, Status: models.ProductDraft},
+	}}
+	return NewService(store, newTestIndex(t)), store
+}
+
+func (s *lifecycleStore) Exists(id int) bool {
+	_, ok := s.products[id]
+	return ok
+}
+
+// Only category 2 exists
+func (s *lifecycleStore) CategoryExists(id int) bool {
+	return id == 2
+}
+
+func (s *lifecycleStore) GetById(id int) (models.Product, error) {
+	p, ok := s.products[id]
+	if !ok {
+		return models.Product{}, sql.ErrNoRows
+	}
+	return p, nil
+}
+
+func (s *lifecycleStore) SetStatus(id int, status string) error {
+	p := s.products[id]
+	p.Status = status
+	s.products[id] = p
+	return nil
+}
+
+// Test deleting archives the product, and restoring puts it back on sale
+func TestDeleteArchivesAndRestore(t *testing.T) {
+	service, store := newLifecycleService(t)
+
+	require.NoError(t, service.Delete(1))
+	assert.Equal(t, models.ProductArchived, store.products[1].Status)
+	assert.ErrorIs(t, service.Delete(99), ErrProductNotFound)
+
+	require.NoError(t, service.Restore(1))
+	assert.Equal(t, models.ProductActive, store.products[1].Status)
+}
+
+// Test only archived products with a category can be restored
+func TestRestoreConflicts(t *testing.T) {
+	service, _ := newLifecycleService(t)
+
+	assert.ErrorIs(t, service.Restore(1), ErrProductNotArchived)
+	assert.ErrorIs(t, service.Restore(3), ErrProductNotArchived)
+	assert.ErrorIs(t, service.Restore(2), ErrRestoreWithoutCategory)
+	assert.ErrorIs(t, service.Restore(99), ErrProductNotFound)
+}
+
+// Test writes can move between draft and active but not archive
+func TestWritesRejectArchivedStatus(t *testing.T) {
+	service, _ := newLifecycleService(t)
+
+	_, err := service.Create(models.Product{Title: "Trackball", Status: models.ProductArchived})
+	assert.ErrorIs(t, err, ErrInvalidProductStatus)
+	assert.ErrorIs(t, service.Update(models.Product{Id: 1, Title: "Mouse", Status: "sold"}), ErrInvalidProductStatus)
+	assert.ErrorIs(t, service.Patch(1, models.ProductPatch{Status: models.Optional[string]{Set: true, Null: true}}), ErrInvalidProductStatus)
+}
+
+// Test writes cannot take a product out of archived, only Restore can
+func TestWritesCannotUnarchive(t *testing.T) {
+	service, store := newLifecycleService(t)
+
+	assert.ErrorIs(t, service.Patch(2, models.ProductPatch{Status: models.Optional[string]{Set: true, Value: models.ProductActive}}), ErrArchivedStatusChange)
+	assert.ErrorIs(t, service.Update(models.Product{Id: 2, Title: "Mouse Pad XL", Status: models.ProductDraft}), ErrArchivedStatusChange)
+	assert.Equal(t, models.ProductArchived, store.products[2].Status)
+}
+
+// Test writes only accept an existing category, and PUT requires one
+func TestWritesCheckCategory(t *testing.T) {
+	service, _ := newLifecycleService(t)
+
+	_, err := service.Create(models.Product{Title: "Trackball", CategId: 9})
+	assert.ErrorIs(t, err, ErrCategoryNotFound)
+	assert.ErrorIs(t, service.Update(models.Product{Id: 1, Title: "Mouse"}), ErrMissingCategory)
+	assert.ErrorIs(t, service.Update(models.Product{Id: 1, Title: "Mouse", CategId: 9}), ErrCategoryNotFound)
+	assert.ErrorIs(t, service.Patch(1, models.ProductPatch{CategId: models.Optional[int]{Set: true, Value: 9}}), ErrCategoryNotFound)
+}
@@ -0,0 +1,121 @@
+package product
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ddessilvestri/ecommerce-go/models"
+)
+
+// snippetWidth is roughly how many bytes of description a snippet shows around the first match
+const snippetWidth = 160
+
+var wordPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// searchTerms are the lowercase words of a search, without boolean-mode operators (+ - * " ~ < > ( ))
+func searchTerms(search string) []string {
+	var terms []string
+	seen := map[string]bool{}
+	for _, word := range wordPattern.FindAllString(strings.ToLower(search), -1) {
+		if !seen[word] {
+			seen[word] = true
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// snippets highlights the terms in the title and in an excerpt of the description.
+// Fields without a match are left out; text is HTML-escaped so the <mark> tags are the only markup.
+func snippets(p models.Product, terms []string) map[string]string {
+	result := map[string]string{}
+	if title, ok := highlight(p.Title, terms); ok {
+		result["prodTitle"] = title
+	}
+	if description, ok := highlight(excerpt(p.Description, terms), terms); ok {
+		result["prodDescription"] = description
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// highlight wraps every word starting with one of the terms in <mark>; ok is false when nothing matched
+func highlight(text string, terms []string) (marked string, ok bool) {
+	var b strings.Builder
+	last := 0
+	for _, loc := range wordPattern.FindAllStringIndex(text, -1) {
+		if !matchesTerm(text[loc[0]:loc[1]], terms) {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[last:loc[0]]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[loc[0]:loc[1]]))
+		b.WriteString("</mark>")
+		last = loc[1]
+		ok = true
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String(), ok
+}
+
+// excerpt cuts text to about snippetWidth bytes around the first matching word, on word boundaries
+func excerpt(text string, terms []string) string {
+	if len(text) <= snippetWidth {
+		return text
+	}
+
+	first := -1
+	for _, loc := range wordPattern.FindAllStringIndex(text, -1) {
+		if matchesTerm(text[loc[0]:loc[1]], terms) {
+			first = loc[0]
+			break
+		}
+	}
+	if first < 0 {
+		return ""
+	}
+
+	start := first - snippetWidth/3
+	if start <= 0 {
+		start = 0
+	} else if space := strings.IndexByte(text[start:first], ' '); space >= 0 {
+		start += space + 1
+	}
+	end := start + snippetWidth
+	if end >= len(text) {
+		end = len(text)
+	} else if space := strings.LastIndexByte(text[first:end], ' '); space > 0 {
+		end = first + space
+	}
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end++
+	}
+
+	cut := strings.TrimSpace(text[start:end])
+	if start > 0 {
+		cut = "…" + cut
+	}
+	if end < len(text) {
+		cut += "…"
+	}
+	return cut
+}
+
+// matchesTerm accepts a word starting with a term, or whose stem is one (as matched by the memory index)
+func matchesTerm(word string, terms []string) bool {
+	word = strings.ToLower(word)
+	stemmed := stem(word)
+	for _, term := range terms {
+		if strings.HasPrefix(word, term) || stemmed == term {
+			return true
+		}
+	}
+	return false
+}
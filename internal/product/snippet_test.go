@@ -0,0 +1,38 @@
+package product
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test boolean operators and duplicates are dropped from the highlighted terms
+func TestSearchTerms(t *testing.T) {
+	assert.Equal(t, []string{"wireless", "mou", "usb"}, searchTerms(`+Wireless -mou* "USB" wireless`))
+}
+
+// Test matches are marked and the rest of the text is escaped
+func TestHighlight(t *testing.T) {
+	marked, ok := highlight("Wireless <Pro> Mouse", []string{"mouse"})
+	assert.True(t, ok)
+	assert.Equal(t, "Wireless &lt;Pro&gt; <mark>Mouse</mark>", marked)
+
+	_, ok = highlight("Keyboard", []string{"mouse"})
+	assert.False(t, ok)
+}
+
+// Test long descriptions are cut around the first match
+func TestSnippets(t *testing.T) {
+	description := strings.Repeat("filler text ", 30) + "with a silent mouse wheel " + strings.Repeat("more words ", 30)
+	got := snippets(models.Product{Title: "Office set", Description: description}, []string{"mouse"})
+
+	assert.NotContains(t, got, "prodTitle")
+	assert.Contains(t, got["prodDescription"], "silent <mark>mouse</mark> wheel")
+	assert.True(t, strings.HasPrefix(got["prodDescription"], "…"))
+	assert.True(t, strings.HasSuffix(got["prodDescription"], "…"))
+	assert.Less(t, len(got["prodDescription"]), len(description))
+
+	assert.Nil(t, snippets(models.Product{Title: "Keyboard"}, []string{"mouse"}))
+}
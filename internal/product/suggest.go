@@ -0,0 +1,245 @@
+package product
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ddessilvestri/ecommerce-go/models"
+)
+
+const (
+	defaultSuggestLimit = 5
+	maxSuggestLimit     = 20
+	maxSearchLength     = 100  // longest search stored or suggested from, in characters: SQ_Query is a varchar(100)
+	popularQueryPool    = 500  // most searched queries kept in memory
+	minPopularHits      = 3    // a query is only suggested once it was searched this many times
+	maxCachedResponses  = 1000 // answers kept per ttl before the cache starts over
+)
+
+// Suggestions feed a search-as-you-type box
+type Suggestions struct {
+	Query      string            `json:"query"`
+	Titles     []string          `json:"titles"`
+	Categories []models.Category `json:"categories"`
+	Queries    []string          `json:"queries"`
+}
+
+// suggestionCache holds the categories, popular queries and recent answers suggestions
+// are served from. Routes are rebuilt whenever the pool is replaced, so one cache is shared by the process.
+type suggestionCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	loadedAt   time.Time
+	categories []models.Category
+	queries    []string // most searched first
+	responses  map[string]Suggestions
+}
+
+var (
+	suggestMu    sync.Mutex
+	suggestCache = &suggestionCache{ttl: time.Minute}
+)
+
+// ConfigureSuggestions sets how long suggestion data is served from memory before it is reloaded
+func ConfigureSuggestions(ttl time.Duration) {
+	suggestMu.Lock()
+	defer suggestMu.Unlock()
+	suggestCache = &suggestionCache{ttl: ttl}
+}
+
+func currentSuggestionCache() *suggestionCache {
+	suggestMu.Lock()
+	defer suggestMu.Unlock()
+	return suggestCache
+}
+
+// ParseSuggestLimit reads ?limit= of the suggestions endpoint, 5 by default and at most 20
+func ParseSuggestLimit(query map[string]string) (int, error) {
+	val := strings.TrimSpace(query["limit"])
+	if val == "" {
+		return defaultSuggestLimit, nil
+	}
+	limit, err := strconv.Atoi(val)
+	if err != nil || limit < 1 || limit > maxSuggestLimit {
+		return 0, models.NewFieldError("limit", fmt.Sprintf("invalid 'limit' parameter: use 1 to %d", maxSuggestLimit))
+	}
+	return limit, nil
+}
+
+// Suggest returns up to limit title completions, categories and popular searches for a typed prefix.
+// Answers come from memory; the database is only read when the cache expires or for a new prefix
+// with the SQL search backend.
+func (s *Service) Suggest(prefix string, limit int) (Suggestions, error) {
+	key := normalizeSearch(prefix)
+	if key == "" {
+		return Suggestions{}, ErrEmptySuggestPrefix
+	}
+	if utf8.RuneCountInString(key) > maxSearchLength {
+		return Suggestions{}, ErrSuggestPrefixTooLong
+	}
+
+	cache := currentSuggestionCache()
+	categories, queries, err := cache.snapshot(s.repo)
+	if err != nil {
+		return Suggestions{}, err
+	}
+
+	responseKey := key + "|" + strconv.Itoa(limit)
+	if cached, ok := cache.response(responseKey); ok {
+		return cached, nil
+	}
+
+	titles, err := s.search.Complete(key, limit)
+	if err != nil {
+		return Suggestions{}, err
+	}
+	result := Suggestions{
+		Query:      key,
+		Titles:     nonNil(titles),
+		Categories: matchCategories(categories, key, limit),
+		Queries:    matchQueries(queries, key, limit),
+	}
+	cache.store(responseKey, result)
+	return result, nil
+}
+
+// recordSearch counts a search for the popular queries; losing a count is not worth failing the search
+func (s *Service) recordSearch(search string) {
+	query := normalizeSearch(search)
+	if query == "" || utf8.RuneCountInString(query) > maxSearchLength {
+		return
+	}
+	if err := s.repo.RecordSearch(query); err != nil {
+		fmt.Println("Recording search failed:", err.Error())
+	}
+}
+
+// PurgeSearch removes a recorded search, so it is no longer suggested.
+// This instance stops suggesting it at once, others when their cache expires.
+func (s *Service) PurgeSearch(search string) error {
+	query := normalizeSearch(search)
+	if query == "" {
+		return ErrEmptyPurgeQuery
+	}
+	if utf8.RuneCountInString(query) > maxSearchLength {
+		return ErrSearchNotFound
+	}
+	if err := s.repo.DeleteSearch(query); err != nil {
+		return err
+	}
+	currentSuggestionCache().forget(query)
+	return nil
+}
+
+// snapshot returns the cached categories and popular queries, reloading them once older than the ttl.
+// A failed reload keeps serving the previous data.
+func (c *suggestionCache) snapshot(repo Storage) ([]models.Category, []string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.loadedAt.IsZero() && time.Since(c.loadedAt) < c.ttl {
+		return c.categories, c.queries, nil
+	}
+
+	categories, err := repo.ListCategories()
+	if err == nil {
+		var queries []string
+		queries, err = repo.PopularSearches(popularQueryPool, minPopularHits)
+		if err == nil {
+			c.categories, c.queries = categories, queries
+			c.responses = map[string]Suggestions{}
+			c.loadedAt = time.Now()
+			return c.categories, c.queries, nil
+		}
+	}
+
+	if c.loadedAt.IsZero() {
+		return nil, nil, err
+	}
+	fmt.Println("Suggestion cache refresh failed, serving the previous data:", err.Error())
+	return c.categories, c.queries, nil
+}
+
+func (c *suggestionCache) response(key string) (Suggestions, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.responses[key]
+	return s, ok
+}
+
+// forget drops a purged query and the answers that may contain it
+func (c *suggestionCache) forget(query string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	kept := make([]string, 0, len(c.queries))
+	for _, q := range c.queries {
+		if q != query {
+			kept = append(kept, q)
+		}
+	}
+	c.queries = kept
+	c.responses = map[string]Suggestions{}
+}
+
+func (c *suggestionCache) store(key string, s Suggestions) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.responses) >= maxCachedResponses {
+		c.responses = map[string]Suggestions{}
+	}
+	c.responses[key] = s
+}
+
+// normalizeSearch lowercases a search and collapses its spaces, so "USB  Cable" and "usb cable" count as one
+func normalizeSearch(search string) string {
+	return strings.Join(strings.Fields(strings.ToLower(search)), " ")
+}
+
+// startsWord reports whether prefix starts text or one of its words
+func startsWord(text, prefix string) bool {
+	return strings.HasPrefix(text, prefix) || strings.Contains(text, " "+prefix)
+}
+
+// matchCategories returns the categories whose name starts with the prefix first, then those with a word starting with it
+func matchCategories(categories []models.Category, prefix string, limit int) []models.Category {
+	matched := []models.Category{}
+	for _, c := range categories {
+		if startsWord(strings.ToLower(c.CategName), prefix) {
+			matched = append(matched, c)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		return strings.HasPrefix(strings.ToLower(matched[i].CategName), prefix) &&
+			!strings.HasPrefix(strings.ToLower(matched[j].CategName), prefix)
+	})
+	if len(matched) > limit {
+		matched = matched[:limit]
+	}
+	return matched
+}
+
+// matchQueries keeps the popularity order of the past searches extending the prefix
+func matchQueries(queries []string, prefix string, limit int) []string {
+	matched := []string{}
+	for _, q := range queries {
+		if q != prefix && startsWord(q, prefix) {
+			matched = append(matched, q)
+			if len(matched) == limit {
+				break
+			}
+		}
+	}
+	return matched
+}
+
+func nonNil(items []string) []string {
+	if items == nil {
+		return []string{}
+	}
+	return items
+}
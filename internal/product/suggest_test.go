@@ -0,0 +1,154 @@
+package product
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// suggestStore serves suggestion data without a database; other Storage methods are not used
+type suggestStore struct {
+	Storage
+	categories []models.Category
+	queries    []string
+	loads      int
+	fail       bool
+	minHits    int
+}
+
+func (s *suggestStore) ListCategories() ([]models.Category, error) {
+	s.loads++
+	if s.fail {
+		return nil, errors.New("connection refused")
+	}
+	return s.categories, nil
+}
+
+func (s *suggestStore) PopularSearches(limit, minHits int) ([]string, error) {
+	s.minHits = minHits
+	return s.queries, nil
+}
+
+func (s *suggestStore) DeleteSearch(search string) error {
+	for i, q := range s.queries {
+		if q == search {
+			s.queries = append(s.queries[:i:i], s.queries[i+1:]...)
+			return nil
+		}
+	}
+	return ErrSearchNotFound
+}
+
+func newSuggestService(t *testing.T, store *suggestStore, ttl time.Duration) *Service {
+	ConfigureSuggestions(ttl)
+	t.Cleanup(func() { ConfigureSuggestions(time.Minute) })
+	return NewService(store, newTestIndex(t))
+}
+
+var suggestCategories = []models.Category{
+	{CategID: 1, CategName: "Computer Mice", CategPath: "mice"},
+	{CategID: 2, CategName: "Mousepads", CategPath: "mousepads"},
+	{CategID: 3, CategName: "Keyboards", CategPath: "keyboards"},
+}
+
+// Test titles, categories and popular queries are matched on the prefix
+func TestSuggest(t *testing.T) {
+	store := &suggestStore{categories: suggestCategories, queries: []string{"mouse", "wireless mouse", "mouse pad", "keyboard"}}
+	service := newSuggestService(t, store, time.Minute)
+
+	got, err := service.Suggest("  MOU ", 5)
+	require.NoError(t, err)
+	assert.Equal(t, "mou", got.Query)
+	assert.Equal(t, []string{"Mouse Pad XL", "Wireless Mouse"}, got.Titles)
+	assert.Equal(t, []models.Category{suggestCategories[1]}, got.Categories)
+	assert.Equal(t, []string{"mouse", "wireless mouse", "mouse pad"}, got.Queries)
+
+	got, err = service.Suggest("mouse", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"wireless mouse"}, got.Queries, "the typed query itself is not suggested")
+
+	got, err = service.Suggest("zzz", 5)
+	require.NoError(t, err)
+	assert.Equal(t, []string{}, got.Titles)
+	assert.Equal(t, []models.Category{}, got.Categories)
+	assert.Equal(t, []string{}, got.Queries)
+}
+
+// Test category names match on any word, names starting with the prefix first
+func TestSuggestCategoryOrder(t *testing.T) {
+	categories := []models.Category{{CategName: "Computer Mice"}, {CategName: "Mice & Trackballs"}}
+	got := matchCategories(categories, "mi", 5)
+	assert.Equal(t, []string{"Mice & Trackballs", "Computer Mice"}, []string{got[0].CategName, got[1].CategName})
+}
+
+// Test the data is loaded once per ttl and kept when a reload fails
+func TestSuggestCache(t *testing.T) {
+	store := &suggestStore{categories: suggestCategories}
+	service := newSuggestService(t, store, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		_, err := service.Suggest("key", 5)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, store.loads)
+
+	service = newSuggestService(t, store, 0)
+	_, err := service.Suggest("key", 5)
+	require.NoError(t, err)
+	store.fail = true
+	got, err := service.Suggest("key", 5)
+	require.NoError(t, err)
+	assert.Equal(t, []models.Category{suggestCategories[2]}, got.Categories)
+}
+
+// Test empty and oversized prefixes and limits are rejected
+func TestSuggestValidation(t *testing.T) {
+	service := newSuggestService(t, &suggestStore{}, time.Minute)
+
+	_, err := service.Suggest("   ", 5)
+	assert.ErrorIs(t, err, ErrEmptySuggestPrefix)
+
+	_, err = service.Suggest(strings.Repeat("a", maxSearchLength+1), 5)
+	assert.ErrorIs(t, err, ErrSuggestPrefixTooLong)
+	_, err = service.Suggest(strings.Repeat("ñ", maxSearchLength), 5)
+	assert.NoError(t, err, "the length is counted in characters, not bytes")
+
+	limit, err := ParseSuggestLimit(map[string]string{})
+	require.NoError(t, err)
+	assert.Equal(t, defaultSuggestLimit, limit)
+	_, err = ParseSuggestLimit(map[string]string{"limit": "50"})
+	assert.Error(t, err)
+}
+
+// Test only queries searched often enough are loaded for suggestions
+func TestSuggestMinimumHits(t *testing.T) {
+	store := &suggestStore{}
+	service := newSuggestService(t, store, time.Minute)
+
+	_, err := service.Suggest("mou", 5)
+	require.NoError(t, err)
+	assert.Equal(t, minPopularHits, store.minHits)
+}
+
+// Test a purged query stops being suggested at once
+func TestPurgeSearch(t *testing.T) {
+	store := &suggestStore{queries: []string{"mouse", "mouse pad"}}
+	service := newSuggestService(t, store, time.Hour)
+
+	got, err := service.Suggest("mou", 5)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mouse", "mouse pad"}, got.Queries)
+
+	require.NoError(t, service.PurgeSearch("  Mouse PAD "))
+	got, err = service.Suggest("mou", 5)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mouse"}, got.Queries)
+
+	assert.ErrorIs(t, service.PurgeSearch("mouse pad"), ErrSearchNotFound)
+	assert.ErrorIs(t, service.PurgeSearch(" "), ErrEmptyPurgeQuery)
+}
@@ -32,18 +32,18 @@ func (h *Handler) Put(requestWithContext models.RequestWithContext) *events.APIG
 
 	err := json.Unmarshal([]byte(body), &stockUpdate)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "Invalid JSON body: "+err.Error())
+		return tools.ErrorResponse(requestWithContext, models.NewValidationError("invalid JSON body").Wrap(err))
 	}
 
 	pId := requestWithContext.RequestPathParameters()["productId"]
 	pIdn, err := strconv.Atoi(pId)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "Invalid ProductId: "+err.Error())
+		return tools.ErrorResponse(requestWithContext, models.NewFieldError("productId", "invalid product Id").Wrap(err))
 	}
 
 	err = h.service.UpdateStock(pIdn, stockUpdate.Delta)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "Error : "+err.Error())
+		return tools.ErrorResponse(requestWithContext, err)
 	}
 
 	return tools.CreateAPIResponse(http.StatusOK, fmt.Sprintf(`{"Stock incremented in %d for ProductId": %d}`, stockUpdate.Delta, pIdn))
@@ -2,10 +2,10 @@ package stock
 
 import (
 	"database/sql"
-	"net/http"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/ddessilvestri/ecommerce-go/routers/route"
 	"github.com/ddessilvestri/ecommerce-go/tools"
 )
 
@@ -21,20 +21,30 @@ func NewRouter(db *sql.DB) *Router {
 	return &Router{handler: handler}
 }
 
+// RegisterRoutes declares the stock endpoints on the route table
+func RegisterRoutes(table *route.Table, db *sql.DB) {
+	r := NewRouter(db)
+	table.Handle(route.PUT, "/stock/{productId}", r.Put).Require(models.RoleAdmin)
+}
+
 // Implements the EntityRouter interface
 func (r *Router) Put(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
 	return r.handler.Put(requestWithContext)
 }
 
 func (r *Router) Post(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
-	return tools.CreateAPIResponse(http.StatusMethodNotAllowed, "not implemented")
+	return tools.ErrorResponse(requestWithContext, models.NewMethodNotAllowedError("not implemented"))
 }
 
 // Future implementations (stubs for now)
 func (r *Router) Get(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
-	return tools.CreateAPIResponse(http.StatusMethodNotAllowed, "not implemented")
+	return tools.ErrorResponse(requestWithContext, models.NewMethodNotAllowedError("not implemented"))
+}
+
+func (r *Router) Patch(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	return tools.ErrorResponse(requestWithContext, models.NewMethodNotAllowedError("not implemented"))
 }
 
 func (r *Router) Delete(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
-	return tools.CreateAPIResponse(http.StatusMethodNotAllowed, "not implemented")
+	return tools.ErrorResponse(requestWithContext, models.NewMethodNotAllowedError("not implemented"))
 }
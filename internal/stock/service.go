@@ -1,7 +1,7 @@
 package stock
 
 import (
-	"errors"
+	"github.com/ddessilvestri/ecommerce-go/models"
 )
 
 // Service provides methods for business logic related to category.
@@ -21,5 +21,5 @@ func (s *Service) UpdateStock(productId, delta int) error {
 
 }
 
-var ErrInvalidProductId = errors.New("invalid product Id: Id < 1 ")
-var ErrInvalidStock = errors.New("invalid stock value")
+var ErrInvalidProductId = models.NewFieldError("productId", "invalid product Id: Id < 1")
+var ErrInvalidStock = models.NewFieldError("delta", "invalid stock value")
@@ -26,19 +26,38 @@ func (h *Handler) Put(requestWithContext models.RequestWithContext) *events.APIG
 
 	err := json.Unmarshal([]byte(body), &u)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return tools.ErrorResponse(requestWithContext, models.NewValidationError("invalid JSON body").Wrap(err))
 	}
 
 	u.UUID, err = context.UserUUIDFromContext(requestWithContext.Context())
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusUnauthorized, "user not found in context")
+		return tools.ErrorResponse(requestWithContext, err)
 	}
 	err = h.service.Update(u)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "error: "+err.Error())
+		return tools.ErrorResponse(requestWithContext, err)
 	}
 
-	return tools.CreateAPIResponse(http.StatusOK, fmt.Sprintf("user updated: %s", u.UUID))
+	return tools.CreateAPIResponse(http.StatusOK, fmt.Sprintf(`{"UpdatedUserUUID": %q}`, u.UUID))
+}
+
+// Patch handles the HTTP PATCH request: a JSON Merge Patch of the current user
+func (h *Handler) Patch(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	var u models.UserPatch
+	if err := tools.ParseMergePatch(requestWithContext.RequestBody(), &u); err != nil {
+		return tools.ErrorResponse(requestWithContext, err)
+	}
+
+	userUUID, err := context.UserUUIDFromContext(requestWithContext.Context())
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, err)
+	}
+	err = h.service.Patch(userUUID, u)
+	if err != nil {
+		return tools.ErrorResponse(requestWithContext, err)
+	}
+
+	return tools.CreateAPIResponse(http.StatusOK, fmt.Sprintf(`{"UpdatedUserUUID": %q}`, userUUID))
 }
 
 func (h *Handler) Get(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
@@ -46,15 +65,15 @@ func (h *Handler) Get(requestWithContext models.RequestWithContext) *events.APIG
 
 	userUUID, err := context.UserUUIDFromContext(requestWithContext.Context())
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusUnauthorized, "user not found in context")
+		return tools.ErrorResponse(requestWithContext, err)
 	}
 	user, err := h.service.GetByUUID(userUUID)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusNotFound, "user not found: "+err.Error())
+		return tools.ErrorResponse(requestWithContext, err)
 	}
 	body, err := json.Marshal(user)
 	if err != nil {
-		return tools.CreateAPIResponse(http.StatusInternalServerError, "error converting to JSON: "+err.Error())
+		return tools.ErrorResponse(requestWithContext, err)
 	}
 	return tools.CreateAPIResponse(http.StatusOK, string(body))
 }
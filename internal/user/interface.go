@@ -4,5 +4,7 @@ import "github.com/ddessilvestri/ecommerce-go/models"
 
 type Storage interface {
 	Update(p models.User) error
+	Patch(uuid string, p models.UserPatch) error
+	Exists(uuid string) bool
 	GetByUUID(uuid string) (models.User, error)
 }
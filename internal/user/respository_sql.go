@@ -16,20 +16,43 @@ func NewSQLRepository(db *sql.DB) Storage {
 	return &repositorySQL{db: db}
 }
 
+// Update replaces both names, so an empty name is stored as sent
 func (r *repositorySQL) Update(u models.User) error {
-	update := squirrel.
+	query, args, err := squirrel.
 		Update("users").
-		PlaceholderFormat(squirrel.Question)
+		PlaceholderFormat(squirrel.Question).
+		Set("User_FirstName", u.FirstName).
+		Set("User_LastName", u.LastName).
+		Where(squirrel.Eq{"User_UUID": u.UUID}).
+		ToSql()
 
-	if u.FirstName != "" {
-		update = update.Set("User_FirstName", u.FirstName)
+	if err != nil {
+		return err
 	}
-	if u.LastName != "" {
-		update = update.Set("User_LastName", u.LastName)
+
+	_, err = r.db.Exec(query, args...)
+	if err != nil {
+		return err
 	}
 
-	query, args, err := update.
-		Where(squirrel.Eq{"User_UUID": u.UUID}).
+	return nil
+}
+
+// Patch only updates the names present in the patch; null names are stored as NULL
+func (r *repositorySQL) Patch(uuid string, u models.UserPatch) error {
+	columns := map[string]interface{}{}
+	if u.FirstName.Set {
+		columns["User_FirstName"] = u.FirstName.SQLValue()
+	}
+	if u.LastName.Set {
+		columns["User_LastName"] = u.LastName.SQLValue()
+	}
+
+	query, args, err := squirrel.
+		Update("users").
+		PlaceholderFormat(squirrel.Question).
+		SetMap(columns).
+		Where(squirrel.Eq{"User_UUID": uuid}).
 		ToSql()
 
 	if err != nil {
@@ -37,11 +60,25 @@ func (r *repositorySQL) Update(u models.User) error {
 	}
 
 	_, err = r.db.Exec(query, args...)
+	return err
+}
+
+func (r *repositorySQL) Exists(uuid string) bool {
+	query, args, err := squirrel.
+		Select("1").
+		From("users").
+		Where(squirrel.Eq{"User_UUID": uuid}).
+		Limit(1).
+		ToSql()
+
 	if err != nil {
-		return err
+		return false
 	}
 
-	return nil
+	var exists int
+	err = r.db.QueryRow(query, args...).Scan(&exists)
+
+	return err == nil
 }
 
 func (r *repositorySQL) GetByUUID(uuid string) (models.User, error) {
@@ -59,10 +96,14 @@ func (r *repositorySQL) GetByUUID(uuid string) (models.User, error) {
 
 	row := r.db.QueryRow(query, args...)
 	var u models.User
-	err = row.Scan(&u.UUID, &u.Email, &u.FirstName, &u.LastName, &u.Status, &u.DateAdd, &u.DateUpg)
+	var firstName, lastName, dateUpg sql.NullString
+	err = row.Scan(&u.UUID, &u.Email, &firstName, &lastName, &u.Status, &u.DateAdd, &dateUpg)
 	if err != nil {
 		return models.User{}, err
 	}
+	u.FirstName = firstName.String
+	u.LastName = lastName.String
+	u.DateUpg = dateUpg.String
 
 	return u, nil
 }
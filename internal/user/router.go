@@ -2,10 +2,10 @@ package user
 
 import (
 	"database/sql"
-	"net/http"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/ddessilvestri/ecommerce-go/routers/route"
 	"github.com/ddessilvestri/ecommerce-go/tools"
 )
 
@@ -21,8 +21,16 @@ func NewRouter(db *sql.DB) *Router {
 	return &Router{handler: handler}
 }
 
+// RegisterRoutes declares the user endpoints on the route table
+func RegisterRoutes(table *route.Table, db *sql.DB) {
+	r := NewRouter(db)
+	table.Handle(route.GET, "/user", r.Get)
+	table.Handle(route.PUT, "/user", r.Put)
+	table.Handle(route.PATCH, "/user", r.Patch)
+}
+
 func (r *Router) Post(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
-	return tools.CreateAPIResponse(http.StatusMethodNotAllowed, "not implemented")
+	return tools.ErrorResponse(requestWithContext, models.NewMethodNotAllowedError("not implemented"))
 }
 
 func (r *Router) Get(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
@@ -33,6 +41,10 @@ func (r *Router) Put(requestWithContext models.RequestWithContext) *events.APIGa
 	return r.handler.Put(requestWithContext)
 }
 
+func (r *Router) Patch(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	return r.handler.Patch(requestWithContext)
+}
+
 func (r *Router) Delete(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
-	return tools.CreateAPIResponse(http.StatusMethodNotAllowed, "not implemented")
+	return tools.ErrorResponse(requestWithContext, models.NewMethodNotAllowedError("not implemented"))
 }
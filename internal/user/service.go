@@ -1,8 +1,6 @@
 package user
 
 import (
-	"errors"
-
 	"github.com/ddessilvestri/ecommerce-go/models"
 )
 
@@ -23,6 +21,17 @@ func (s *Service) Update(u models.User) error {
 	return s.repo.Update(u)
 }
 
+// Patch applies a JSON Merge Patch of the user's names; null clears a name
+func (s *Service) Patch(uuid string, u models.UserPatch) error {
+	if uuid == "" {
+		return ErrInvalidId
+	}
+	if !s.repo.Exists(uuid) {
+		return ErrUserNotFound
+	}
+	return s.repo.Patch(uuid, u)
+}
+
 func (s *Service) GetByUUID(uuid string) (models.User, error) {
 	if uuid == "" {
 		return models.User{}, ErrInvalidId
@@ -31,5 +40,9 @@ func (s *Service) GetByUUID(uuid string) (models.User, error) {
 	return s.repo.GetByUUID(uuid)
 }
 
-var ErrMissingNames = errors.New("invalid user: first name or last name is required")
-var ErrInvalidId = errors.New("invalid user id")
+var ErrMissingNames = models.NewValidationError("invalid user: first name or last name is required",
+	models.FieldError{Field: "firstName", Message: "required"},
+	models.FieldError{Field: "lastName", Message: "required"},
+)
+var ErrInvalidId = models.NewUnauthorizedError("invalid user id")
+var ErrUserNotFound = models.NewNotFoundError("user not found")
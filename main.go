@@ -2,15 +2,14 @@ package main
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 
-	"github.com/ddessilvestri/ecommerce-go/awsgo"
-	"github.com/ddessilvestri/ecommerce-go/db"
-	"github.com/ddessilvestri/ecommerce-go/internal/config"
+	"github.com/ddessilvestri/ecommerce-go/internal/app"
 	"github.com/ddessilvestri/ecommerce-go/routers"
-	"github.com/ddessilvestri/ecommerce-go/secretm"
+	"github.com/ddessilvestri/ecommerce-go/tools"
 )
 
 func main() {
@@ -18,34 +17,14 @@ func main() {
 }
 
 func LambdaExec(ctx context.Context, request events.APIGatewayV2HTTPRequest) (*events.APIGatewayProxyResponse, error) {
-	awsgo.AWSInit()
-
-	conf, err := config.LoadConfig()
-	if err != nil {
-		panic("Config load failed: " + err.Error())
-	}
-
-	// Read secrets
-	secret, err := secretm.GetSecret(conf.SecretName)
-	if err != nil {
-		return &events.APIGatewayProxyResponse{
-			StatusCode: 500,
-			Body:       "Failed to read secret: " + err.Error(),
-		}, nil
-	}
-
-	// Connect to DB using secret + config
-	sqlDB, err := db.DbConnectAndReturn(secret, conf.DBName)
+	// Config, secret and connection pool are built once and reused while the Lambda is warm
+	application, err := app.Get(ctx)
 	if err != nil {
-		return &events.APIGatewayProxyResponse{
-			StatusCode: 500,
-			Body:       "Database connection error: " + err.Error(),
-		}, nil
+		return tools.CreateErrorResponse(request.RequestContext.RequestID, fmt.Errorf("application initialization error: %w", err)), nil
 	}
-	defer sqlDB.Close()
 
 	// Route
-	response := routers.Router(request, conf.UrlPrefix, sqlDB)
+	response := routers.Router(request, application.Config.UrlPrefix, application.Routes, application.DB)
 
 	return response, nil
 }
@@ -0,0 +1,4 @@
+-- Full-text search over product titles and descriptions (MATCH ... AGAINST in internal/product/filter.go).
+-- Words shorter than innodb_ft_min_token_size (3 by default) and InnoDB stopwords are not indexed.
+ALTER TABLE `products`
+  ADD FULLTEXT KEY `Prod_FullText` (`Prod_Title`, `Prod_Description`);
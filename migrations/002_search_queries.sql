@@ -0,0 +1,8 @@
+-- Searches customers ran, counted so the suggestions endpoint can offer popular queries.
+CREATE TABLE IF NOT EXISTS `search_queries` (
+  `SQ_Query` varchar(100) NOT NULL COMMENT 'Normalized search text: lowercase, single spaces',
+  `SQ_Hits` int unsigned NOT NULL DEFAULT '1' COMMENT 'Times the search was run and found products',
+  `SQ_LastSearched` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`SQ_Query`),
+  KEY `SQ_Hits` (`SQ_Hits`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
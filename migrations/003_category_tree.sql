@@ -0,0 +1,8 @@
+-- Categories form a tree: each one has an optional parent, a position among its siblings
+-- and its depth (0 for top-level categories), kept in step by category.Service.
+ALTER TABLE `category`
+  ADD COLUMN `Categ_ParentId` int unsigned DEFAULT NULL COMMENT 'Parent category, NULL at the top level' AFTER `Categ_Path`,
+  ADD COLUMN `Categ_Position` int NOT NULL DEFAULT '0' COMMENT 'Order among siblings, ties sorted by name' AFTER `Categ_ParentId`,
+  ADD COLUMN `Categ_Depth` tinyint unsigned NOT NULL DEFAULT '0' COMMENT 'Distance from the top level' AFTER `Categ_Position`,
+  ADD KEY `Categ_ParentId` (`Categ_ParentId`, `Categ_Position`),
+  ADD CONSTRAINT `FK_Category_Parent` FOREIGN KEY (`Categ_ParentId`) REFERENCES `category` (`Categ_Id`);
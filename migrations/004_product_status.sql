@@ -0,0 +1,4 @@
+-- Product lifecycle. Archived products keep their row, so orders_detail still resolves them.
+ALTER TABLE `products`
+  ADD COLUMN `Prod_Status` enum('draft','active','archived') NOT NULL DEFAULT 'active' COMMENT 'Lifecycle: draft, active or archived' AFTER `Prod_Stock`,
+  ADD KEY `Prod_Status` (`Prod_Status`);
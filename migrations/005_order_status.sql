@@ -0,0 +1,26 @@
+-- Order lifecycle. Transitions are enforced by order.Service; every change is kept in order_status_history.
+ALTER TABLE `orders`
+  ADD COLUMN `Order_Status` enum('pending','paid','processing','shipped','delivered','cancelled','refunded') NOT NULL DEFAULT 'pending' COMMENT 'Lifecycle, see order.Service' AFTER `Order_Total`,
+  ADD COLUMN `Order_StockReserved` tinyint(1) NOT NULL DEFAULT '1' COMMENT 'Quantities were taken from Prod_Stock when placed' AFTER `Order_Status`,
+  ADD KEY `Order_Status` (`Order_Status`);
+
+-- Orders placed before stock was reserved must not give back stock they never took
+UPDATE `orders` SET `Order_StockReserved` = 0;
+
+CREATE TABLE IF NOT EXISTS `order_status_history` (
+  `OSH_Id` int unsigned NOT NULL AUTO_INCREMENT,
+  `OSH_OrderId` int unsigned NOT NULL,
+  `OSH_From` varchar(20) DEFAULT NULL COMMENT 'NULL when the order was placed',
+  `OSH_To` varchar(20) NOT NULL,
+  `OSH_Actor` char(36) DEFAULT NULL COMMENT 'UUID of the user who made the change',
+  `OSH_ActorRole` varchar(20) NOT NULL DEFAULT 'system' COMMENT 'customer, admin or system',
+  `OSH_Note` varchar(255) DEFAULT NULL,
+  `OSH_Date` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`OSH_Id`),
+  KEY `OSH_OrderId` (`OSH_OrderId`,`OSH_Date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- Orders placed before this migration keep the default pending status, so they can still be paid,
+-- cancelled or amended. Their timeline starts with a system entry on their order date.
+INSERT INTO `order_status_history` (`OSH_OrderId`, `OSH_From`, `OSH_To`, `OSH_ActorRole`, `OSH_Note`, `OSH_Date`)
+SELECT `Order_Id`, NULL, 'pending', 'system', 'placed before status tracking', COALESCE(`Order_Date`, NOW()) FROM `orders`;
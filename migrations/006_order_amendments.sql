@@ -0,0 +1,13 @@
+-- One row per amendment of an order's lines, written by order.Service.Amend in the same transaction as the change.
+CREATE TABLE IF NOT EXISTS `order_amendments` (
+  `OA_Id` int unsigned NOT NULL AUTO_INCREMENT,
+  `OA_OrderId` int unsigned NOT NULL,
+  `OA_Lines` json NOT NULL COMMENT 'Changed lines: prodId with the quantity before (from) and after (to)',
+  `OA_AddId` int unsigned DEFAULT NULL COMMENT 'New address, NULL when unchanged',
+  `OA_TotalBefore` decimal(20,2) NOT NULL,
+  `OA_TotalAfter` decimal(20,2) NOT NULL,
+  `OA_Actor` char(36) DEFAULT NULL COMMENT 'UUID of the user who amended the order',
+  `OA_Date` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`OA_Id`),
+  KEY `OA_OrderId` (`OA_OrderId`,`OA_Date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
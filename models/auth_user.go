@@ -1,5 +1,18 @@
 package models
 
+import "time"
+
+// AuthUser is the identity carried by a verified token
 type AuthUser struct {
-	UUID string
+	UUID      string // "username" claim, used as users.User_UUID
+	Sub       string
+	Username  string
+	Email     string   // only present in id tokens
+	Groups    []string // "cognito:groups"
+	Scopes    []string // "scope", split on spaces
+	AuthTime  time.Time
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+
+	Role Role // resolved from the users table when a route requires a role
 }
@@ -0,0 +1,10 @@
+package models
+
+// Cursor marks the last row of a keyset page: its sort key value and id.
+// It is sent to clients as an opaque string (see tools.EncodeCursor).
+type Cursor struct {
+	SortBy string      `json:"s"`
+	Order  string      `json:"o"`
+	Value  interface{} `json:"v"`
+	Id     int         `json:"i"`
+}
@@ -0,0 +1,81 @@
+package models
+
+// ErrorKind classifies domain errors so they can be mapped to HTTP statuses in one place
+type ErrorKind string
+
+const (
+	ErrKindValidation       ErrorKind = "validation_error"
+	ErrKindNotFound         ErrorKind = "not_found"
+	ErrKindConflict         ErrorKind = "conflict"
+	ErrKindForbidden        ErrorKind = "forbidden"
+	ErrKindUnauthorized     ErrorKind = "unauthorized"
+	ErrKindMethodNotAllowed ErrorKind = "method_not_allowed"
+)
+
+// FieldError points at the request field that failed validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// DomainError is the typed error returned by services and handlers
+type DomainError struct {
+	Kind    ErrorKind
+	Message string
+	Fields  []FieldError
+	Err     error // optional cause
+}
+
+func (e *DomainError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *DomainError) Unwrap() error {
+	return e.Err
+}
+
+// NewValidationError reports invalid input, optionally pointing at the offending fields
+func NewValidationError(message string, fields ...FieldError) *DomainError {
+	return &DomainError{Kind: ErrKindValidation, Message: message, Fields: fields}
+}
+
+// NewFieldError reports a single invalid field
+func NewFieldError(field, message string) *DomainError {
+	return NewValidationError(message, FieldError{Field: field, Message: message})
+}
+
+func NewNotFoundError(message string) *DomainError {
+	return &DomainError{Kind: ErrKindNotFound, Message: message}
+}
+
+func NewConflictError(message string) *DomainError {
+	return &DomainError{Kind: ErrKindConflict, Message: message}
+}
+
+func NewForbiddenError(message string) *DomainError {
+	return &DomainError{Kind: ErrKindForbidden, Message: message}
+}
+
+func NewUnauthorizedError(message string) *DomainError {
+	return &DomainError{Kind: ErrKindUnauthorized, Message: message}
+}
+
+func NewMethodNotAllowedError(message string) *DomainError {
+	return &DomainError{Kind: ErrKindMethodNotAllowed, Message: message}
+}
+
+// Wrap attaches a cause to a copy of the error, keeping its kind and message
+func (e *DomainError) Wrap(cause error) *DomainError {
+	wrapped := *e
+	wrapped.Err = cause
+	return &wrapped
+}
+
+// Is matches errors of the same kind and message, so wrapped copies still match their sentinel
+func (e *DomainError) Is(target error) bool {
+	t, ok := target.(*DomainError)
+	return ok && t.Kind == e.Kind && t.Message == e.Message
+}
@@ -15,23 +15,66 @@ type SignUp struct {
 }
 
 type Category struct {
-	CategID   int    `json:"categID"`
-	CategName string `json:"categName"`
-	CategPath string `json:"categPath"`
+	CategID       int        `json:"categID"`
+	CategName     string     `json:"categName"`
+	CategPath     string     `json:"categPath"`
+	CategParentID *int       `json:"categParentID"` // nil for top-level categories
+	CategPosition int        `json:"categPosition"` // order among siblings
+	CategDepth    int        `json:"categDepth"`    // 0 at the top level, computed from the parent
+	Children      []Category `json:"children,omitempty"`
+
+	Stats      *CategoryStats `json:"stats,omitempty"`      // products of the category itself, with ?stats=true
+	TotalStats *CategoryStats `json:"totalStats,omitempty"` // products of the category and all its subcategories
+}
+
+// CategoryStats summarises the active products of a category; prices are null without products
+type CategoryStats struct {
+	Products int      `json:"products"`
+	InStock  int      `json:"inStock"`
+	MinPrice *float64 `json:"minPrice"`
+	MaxPrice *float64 `json:"maxPrice"`
+}
+
+// CategoryDelete says what happens to the products of a deleted category;
+// with neither option the delete is refused while products remain
+type CategoryDelete struct {
+	ReassignTo      int  // move the products to this category
+	ArchiveProducts bool // archive the products and clear their category
+}
+
+// CategoryMove places a category under a new parent (nil for the top level) at a position among its siblings
+type CategoryMove struct {
+	ParentID *int `json:"parentID"`
+	Position int  `json:"position"`
 }
 
 type Product struct {
-	Id          int     `json:"prodID"`
-	Title       string  `json:"prodTitle"`
-	Description string  `json:"prodDescription"`
-	CreatedAt   string  `json:"prodCreatedAt"`
-	Updated     string  `json:"prodUpdated"`
-	Price       float64 `json:"prodPrice,omitempty"`
-	Stock       int     `json:"prodStock"`
-	CategId     int     `json:"prodCategId"`
-	Path        string  `json:"prodPath"`
-	Search      string  `json:"search,omitempty"`
-	CategPath   string  `json:"categPath,omitempty"`
+	Id          int               `json:"prodID"`
+	Title       string            `json:"prodTitle"`
+	Description string            `json:"prodDescription"`
+	CreatedAt   string            `json:"prodCreatedAt"`
+	Updated     string            `json:"prodUpdated"`
+	Price       float64           `json:"prodPrice,omitempty"`
+	Stock       int               `json:"prodStock"`
+	CategId     int               `json:"prodCategId"`
+	Path        string            `json:"prodPath"`
+	Search      string            `json:"search,omitempty"`
+	CategPath   string            `json:"categPath,omitempty"`
+	Status      string            `json:"prodStatus"`         // ProductDraft, ProductActive or ProductArchived
+	Score       float64           `json:"score,omitempty"`    // full-text relevance, only set by searches
+	Snippets    map[string]string `json:"snippets,omitempty"` // matched terms wrapped in <mark>, keyed by field
+}
+
+// Product lifecycle (Prod_Status)
+const (
+	ProductDraft    = "draft"    // being prepared, only visible to admins
+	ProductActive   = "active"   // on sale
+	ProductArchived = "archived" // withdrawn: hidden from the storefront, the row stays for order history
+)
+
+// ProductVisible is the storefront rule shared by listings, lookups by id or slug and breadcrumbs
+func ProductVisible(status string) bool {
+	return status == ProductActive
 }
 
 type Address struct {
@@ -46,11 +89,13 @@ type Address struct {
 }
 
 type OrdersDetails struct {
-	Id       int     `json:"id"`
-	OrderId  int     `json:"orderId"`
-	ProdId   int     `json:"prodId"`
-	Quantity int     `json:"quantity"`
-	Price    float64 `json:"price"`
+	Id        int     `json:"id"`
+	OrderId   int     `json:"orderId"`
+	ProdId    int     `json:"prodId"`
+	ProdTitle string  `json:"prodTitle,omitempty"` // read with the order, so history doesn't depend on the product being on sale
+	Quantity  int     `json:"quantity"`
+	Price     float64 `json:"price"`     // unit price, taken from the catalog when the order is placed
+	LineTotal float64 `json:"lineTotal"` // Price * Quantity, computed
 }
 
 type Orders struct {
@@ -59,9 +104,62 @@ type Orders struct {
 	AddId        int     `json:"orderAddId"`
 	Date         string  `json:"orderDate"`
 	Total        float64 `json:"orderTotal"`
+	Status       string  `json:"orderStatus"` // one of the OrderStatus* constants
 	OrderDetails []OrdersDetails
 }
 
+// Order lifecycle (Order_Status); the allowed transitions live in order.Service
+const (
+	OrderStatusPending    = "pending"
+	OrderStatusPaid       = "paid"
+	OrderStatusProcessing = "processing"
+	OrderStatusShipped    = "shipped"
+	OrderStatusDelivered  = "delivered"
+	OrderStatusCancelled  = "cancelled"
+	OrderStatusRefunded   = "refunded"
+)
+
+// OrderStatusChange is one entry of an order timeline (order_status_history)
+type OrderStatusChange struct {
+	From      string `json:"from,omitempty"` // empty for the entry created with the order
+	To        string `json:"to"`
+	Actor     string `json:"actor,omitempty"` // UUID of the user who made the change
+	ActorRole string `json:"actorRole"`       // customer, admin or system
+	Note      string `json:"note,omitempty"`
+	Date      string `json:"date"`
+}
+
+// OrderStatusUpdate is the body of POST /order/{id}/status
+type OrderStatusUpdate struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
+
+// OrderLineChange is the quantity of a product before and after an amendment: From 0 is an added line, To 0 a removed one
+type OrderLineChange struct {
+	ProdId int `json:"prodId"`
+	From   int `json:"from"`
+	To     int `json:"to"`
+}
+
+// OrderAmendment records one change to the lines of an order (order_amendments)
+type OrderAmendment struct {
+	OrderId     int               `json:"orderId"`
+	Lines       []OrderLineChange `json:"lines"`
+	AddId       int               `json:"orderAddId,omitempty"` // new address, 0 when unchanged
+	TotalBefore float64           `json:"totalBefore"`
+	TotalAfter  float64           `json:"totalAfter"`
+	Actor       string            `json:"actor,omitempty"`
+	Date        string            `json:"date,omitempty"`
+}
+
+// OrderTimeline is the current status of an order and how it got there, oldest change first
+type OrderTimeline struct {
+	OrderId int                 `json:"orderId"`
+	Status  string              `json:"orderStatus"`
+	History []OrderStatusChange `json:"history"`
+}
+
 type User struct {
 	UUID      string `json:"uuid"`
 	Email     string `json:"email"`
@@ -0,0 +1,42 @@
+package models
+
+// Page is the envelope returned by every list endpoint
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	Page       int    `json:"page,omitempty"` // zero in cursor mode
+	Limit      int    `json:"limit"`
+	Total      int    `json:"total"`
+	Capped     bool   `json:"capped,omitempty"` // total stops at the search hit limit
+	HasNext    bool   `json:"hasNext"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	Next       string `json:"next,omitempty"`
+	Prev       string `json:"prev,omitempty"`
+}
+
+// NewPage wraps one page of items with the total number of matching rows
+func NewPage[T any](items []T, page, limit, total int) Page[T] {
+	if items == nil {
+		items = []T{}
+	}
+	return Page[T]{
+		Items:   items,
+		Page:    page,
+		Limit:   limit,
+		Total:   total,
+		HasNext: page*limit < total,
+	}
+}
+
+// NewCursorPage wraps a page fetched after a cursor; there are no page numbers in cursor mode
+func NewCursorPage[T any](items []T, limit, total int, nextCursor string) Page[T] {
+	if items == nil {
+		items = []T{}
+	}
+	return Page[T]{
+		Items:      items,
+		Limit:      limit,
+		Total:      total,
+		HasNext:    nextCursor != "",
+		NextCursor: nextCursor,
+	}
+}
@@ -0,0 +1,58 @@
+package models
+
+import "encoding/json"
+
+// Optional is a JSON Merge Patch (RFC 7396) field:
+// absent (Set is false), explicitly null (Null is true) or a value, including zero values.
+type Optional[T any] struct {
+	Set   bool
+	Null  bool
+	Value T
+}
+
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		o.Null = true
+		return nil
+	}
+	return json.Unmarshal(data, &o.Value)
+}
+
+// SQLValue is the value to store: nil clears the column
+func (o Optional[T]) SQLValue() interface{} {
+	if o.Null {
+		return nil
+	}
+	return o.Value
+}
+
+type ProductPatch struct {
+	Title       Optional[string]  `json:"prodTitle"`
+	Description Optional[string]  `json:"prodDescription"`
+	Price       Optional[float64] `json:"prodPrice"`
+	Stock       Optional[int]     `json:"prodStock"`
+	CategId     Optional[int]     `json:"prodCategId"`
+	Path        Optional[string]  `json:"prodPath"`
+	Status      Optional[string]  `json:"prodStatus"`
+}
+
+type CategoryPatch struct {
+	CategName Optional[string] `json:"categName"`
+	CategPath Optional[string] `json:"categPath"`
+}
+
+type AddressPatch struct {
+	Title      Optional[string] `json:"title"`
+	Name       Optional[string] `json:"name"`
+	Address    Optional[string] `json:"address"`
+	City       Optional[string] `json:"city"`
+	State      Optional[string] `json:"state"`
+	PostalCode Optional[string] `json:"postalCode"`
+	Phone      Optional[string] `json:"phone"`
+}
+
+type UserPatch struct {
+	FirstName Optional[string] `json:"firstName"`
+	LastName  Optional[string] `json:"lastName"`
+}
@@ -22,6 +22,12 @@ func (r RequestWithContext) Context() context.Context {
 	return r.ctx
 }
 
+// WithContext returns a copy of the request carrying ctx
+func (r RequestWithContext) WithContext(ctx context.Context) RequestWithContext {
+	r.ctx = ctx
+	return r
+}
+
 func (r RequestWithContext) Request() events.APIGatewayV2HTTPRequest {
 	return r.req
 }
@@ -34,6 +40,16 @@ func (r RequestWithContext) RequestQueryStringParameters() map[string]string {
 	return r.req.QueryStringParameters
 }
 
+// RequestPath returns the raw path as sent by the client, URL prefix included
+func (r RequestWithContext) RequestPath() string {
+	return r.req.RawPath
+}
+
 func (r RequestWithContext) RequestPathParameters() map[string]string {
 	return r.req.PathParameters
 }
+
+// RequestID returns the API Gateway request id, echoed back in error responses
+func (r RequestWithContext) RequestID() string {
+	return r.req.RequestContext.RequestID
+}
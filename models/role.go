@@ -0,0 +1,30 @@
+package models
+
+// Role is the authorization level of a user, derived from users.User_Status
+type Role string
+
+const (
+	RoleAdmin    Role = "admin"
+	RoleCustomer Role = "customer"
+)
+
+// RoleFromStatus maps User_Status to a role: 0 means admin, anything else is a customer
+func RoleFromStatus(status int) Role {
+	if status == 0 {
+		return RoleAdmin
+	}
+	return RoleCustomer
+}
+
+// Satisfies reports whether a user holding r can access a route requiring required.
+// Admins can reach every route; an empty requirement is satisfied by any role.
+func (r Role) Satisfies(required Role) bool {
+	switch {
+	case required == "":
+		return true
+	case r == RoleAdmin:
+		return true
+	default:
+		return r == required
+	}
+}
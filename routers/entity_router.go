@@ -9,5 +9,6 @@ type EntityRouter interface {
 	Get(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse
 	Post(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse
 	Put(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse
+	Patch(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse
 	Delete(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse
 }
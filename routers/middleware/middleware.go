@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	authContext "github.com/ddessilvestri/ecommerce-go/auth/context"
+	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/ddessilvestri/ecommerce-go/routers/route"
+	"github.com/ddessilvestri/ecommerce-go/tools"
+)
+
+// AuthFunc extracts the authenticated user from the request headers
+type AuthFunc func(header map[string]string) (*models.AuthUser, error)
+
+// RoleResolver looks up the role of a user by UUID
+type RoleResolver func(userUUID string) (models.Role, error)
+
+// Recover turns a panic in any later handler into a 500 response
+func Recover() route.Middleware {
+	return func(next route.HandlerFunc) route.HandlerFunc {
+		return func(requestWithContext models.RequestWithContext) (response *events.APIGatewayProxyResponse) {
+			defer func() {
+				if rec := recover(); rec != nil {
+					fmt.Printf("Recovered from panic: %v\n%s\n", rec, debug.Stack())
+					response = tools.ErrorResponse(requestWithContext, fmt.Errorf("panic: %v", rec))
+				}
+			}()
+			return next(requestWithContext)
+		}
+	}
+}
+
+// Timing logs method, path, status and elapsed time of every request
+func Timing() route.Middleware {
+	return func(next route.HandlerFunc) route.HandlerFunc {
+		return func(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+			start := time.Now()
+			response := next(requestWithContext)
+
+			request := requestWithContext.Request()
+			status := 0
+			if response != nil {
+				status = response.StatusCode
+			}
+			fmt.Printf("%s %s -> %d (%s)\n", request.RequestContext.HTTP.Method, request.RawPath, status, time.Since(start))
+			return response
+		}
+	}
+}
+
+// Authenticate resolves the user with extract and stores it in the request context.
+// Requests without a valid user are rejected with 401.
+func Authenticate(extract AuthFunc) route.Middleware {
+	return func(next route.HandlerFunc) route.HandlerFunc {
+		return func(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+			authUser, err := extract(requestWithContext.Request().Headers)
+			if err != nil {
+				return tools.ErrorResponse(requestWithContext, models.NewUnauthorizedError("unable to authenticate user").Wrap(err))
+			}
+			ctx := authContext.WithUser(requestWithContext.Context(), authUser)
+			return next(requestWithContext.WithContext(ctx))
+		}
+	}
+}
+
+// Authorize resolves the role of the authenticated user and rejects with 403
+// when it does not satisfy required. It must run after Authenticate.
+func Authorize(resolve RoleResolver, required models.Role) route.Middleware {
+	return func(next route.HandlerFunc) route.HandlerFunc {
+		return func(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+			authUser, ok := authContext.UserFromContext(requestWithContext.Context())
+			if !ok || authUser == nil {
+				return tools.ErrorResponse(requestWithContext, authContext.ErrUserNotInContext)
+			}
+
+			role, err := resolve(authUser.UUID)
+			if err != nil {
+				fmt.Println("Unable to resolve role for", authUser.UUID, ":", err.Error())
+				return tools.ErrorResponse(requestWithContext, models.NewForbiddenError("access denied"))
+			}
+			authUser.Role = role
+
+			if !role.Satisfies(required) {
+				return tools.ErrorResponse(requestWithContext, models.NewForbiddenError("access denied: "+string(required)+" role required"))
+			}
+			return next(requestWithContext)
+		}
+	}
+}
+
+// RequireGroup rejects with 403 users outside the given Cognito group.
+// It must run after Authenticate.
+func RequireGroup(group string) route.Middleware {
+	return func(next route.HandlerFunc) route.HandlerFunc {
+		return func(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+			if !authContext.HasGroup(requestWithContext.Context(), group) {
+				return tools.ErrorResponse(requestWithContext, models.NewForbiddenError("access denied: group '"+group+"' required"))
+			}
+			return next(requestWithContext)
+		}
+	}
+}
+
+// RequireScope rejects with 403 tokens that were not granted scope.
+// It must run after Authenticate.
+func RequireScope(scope string) route.Middleware {
+	return func(next route.HandlerFunc) route.HandlerFunc {
+		return func(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+			if !authContext.HasScope(requestWithContext.Context(), scope) {
+				return tools.ErrorResponse(requestWithContext, models.NewForbiddenError("access denied: scope '"+scope+"' required"))
+			}
+			return next(requestWithContext)
+		}
+	}
+}
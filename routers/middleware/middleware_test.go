@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	authContext "github.com/ddessilvestri/ecommerce-go/auth/context"
+	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/ddessilvestri/ecommerce-go/routers/route"
+	"github.com/ddessilvestri/ecommerce-go/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRequest() models.RequestWithContext {
+	request := events.APIGatewayV2HTTPRequest{RawPath: "/product/1"}
+	request.RequestContext.HTTP.Method = http.MethodGet
+	request.RequestContext.RequestID = "req-42"
+	return models.NewRequestWithContext(request, context.Background())
+}
+
+func withUser(uuid string) models.RequestWithContext {
+	request := newRequest()
+	return request.WithContext(authContext.WithUser(request.Context(), &models.AuthUser{UUID: uuid}))
+}
+
+func ok(models.RequestWithContext) *events.APIGatewayProxyResponse {
+	return tools.CreateAPIResponse(http.StatusOK, "ok")
+}
+
+func errorBody(t *testing.T, response *events.APIGatewayProxyResponse) tools.ErrorPayload {
+	var body tools.ErrorBody
+	require.NoError(t, json.Unmarshal([]byte(response.Body), &body))
+	return body.Error
+}
+
+func rolesByUUID(userUUID string) (models.Role, error) {
+	switch userUUID {
+	case "admin-1":
+		return models.RoleAdmin, nil
+	case "customer-1":
+		return models.RoleCustomer, nil
+	default:
+		return "", errors.New("user not found")
+	}
+}
+
+// Test a panic in the handler becomes a 500 error envelope carrying the request id
+func TestRecover(t *testing.T) {
+	handler := route.Chain(func(models.RequestWithContext) *events.APIGatewayProxyResponse {
+		panic("boom")
+	}, Recover())
+
+	response := handler(newRequest())
+
+	require.NotNil(t, response)
+	assert.Equal(t, http.StatusInternalServerError, response.StatusCode)
+	payload := errorBody(t, response)
+	assert.Equal(t, "internal_error", payload.Code)
+	assert.Equal(t, "internal server error", payload.Message)
+	assert.Equal(t, "req-42", payload.RequestID)
+}
+
+// Test Timing hands back the response of the handler untouched
+func TestTiming(t *testing.T) {
+	response := route.Chain(ok, Timing())(newRequest())
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "ok", response.Body)
+}
+
+// Test a failed extraction is a 401 and a successful one puts the user in the context
+func TestAuthenticate(t *testing.T) {
+	rejected := route.Chain(ok, Authenticate(func(map[string]string) (*models.AuthUser, error) {
+		return nil, errors.New("expired token")
+	}))
+	response := rejected(newRequest())
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+	assert.Equal(t, string(models.ErrKindUnauthorized), errorBody(t, response).Code)
+
+	var seen *models.AuthUser
+	accepted := route.Chain(func(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+		seen, _ = authContext.UserFromContext(requestWithContext.Context())
+		return ok(requestWithContext)
+	}, Authenticate(func(map[string]string) (*models.AuthUser, error) {
+		return &models.AuthUser{UUID: "customer-1"}, nil
+	}))
+	response = accepted(newRequest())
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	require.NotNil(t, seen)
+	assert.Equal(t, "customer-1", seen.UUID)
+}
+
+// Test a role mismatch or an unknown user is a 403, a missing user a 401
+func TestAuthorize(t *testing.T) {
+	handler := route.Chain(ok, Authorize(rolesByUUID, models.RoleAdmin))
+
+	tests := []struct {
+		name    string
+		request models.RequestWithContext
+		status  int
+	}{
+		{name: "Admin", request: withUser("admin-1"), status: http.StatusOK},
+		{name: "Customer", request: withUser("customer-1"), status: http.StatusForbidden},
+		{name: "Unknown user", request: withUser("ghost"), status: http.StatusForbidden},
+		{name: "Not authenticated", request: newRequest(), status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := handler(tt.request)
+			assert.Equal(t, tt.status, response.StatusCode)
+		})
+	}
+}
+
+// Test Authorize stores the resolved role on the user for later handlers
+func TestAuthorizeSetsRole(t *testing.T) {
+	request := withUser("admin-1")
+	route.Chain(ok, Authorize(rolesByUUID, models.RoleCustomer))(request)
+
+	authUser, _ := authContext.UserFromContext(request.Context())
+	assert.Equal(t, models.RoleAdmin, authUser.Role)
+}
+
+// Test middlewares run in the order given, outermost first, and unwind in reverse
+func TestChainOrder(t *testing.T) {
+	var calls []string
+	record := func(name string) route.Middleware {
+		return func(next route.HandlerFunc) route.HandlerFunc {
+			return func(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+				calls = append(calls, name+" in")
+				response := next(requestWithContext)
+				calls = append(calls, name+" out")
+				return response
+			}
+		}
+	}
+
+	handler := route.Chain(func(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+		calls = append(calls, "handler")
+		return ok(requestWithContext)
+	}, record("first"), record("second"), record("third"))
+	handler(newRequest())
+
+	assert.Equal(t, []string{"first in", "second in", "third in", "handler", "third out", "second out", "first out"}, calls)
+}
+
+// Test Authenticate rejects before Authorize or the handler run when chained like a protected route
+func TestChainStopsAtFirstRejection(t *testing.T) {
+	reached := false
+	handler := route.Chain(func(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+		reached = true
+		return ok(requestWithContext)
+	},
+		Recover(),
+		Authenticate(func(map[string]string) (*models.AuthUser, error) { return nil, errors.New("no token") }),
+		Authorize(func(string) (models.Role, error) {
+			t.Fatal("Authorize must not run after a failed authentication")
+			return "", nil
+		}, models.RoleAdmin),
+	)
+
+	response := handler(newRequest())
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+	assert.False(t, reached)
+}
@@ -0,0 +1,182 @@
+package route
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ddessilvestri/ecommerce-go/models"
+)
+
+// HTTP method constants
+const (
+	GET    = "GET"
+	POST   = "POST"
+	PUT    = "PUT"
+	PATCH  = "PATCH"
+	DELETE = "DELETE"
+)
+
+// HandlerFunc is the signature shared by every entity handler
+type HandlerFunc func(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse
+
+// Middleware wraps a handler with cross-cutting behaviour (auth, logging, recovery...)
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// Chain wraps handler so that middlewares run in the order given
+func Chain(handler HandlerFunc, middlewares ...Middleware) HandlerFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// Route binds a method and a pattern like "/product/{id}" to a handler
+type Route struct {
+	Method     string
+	Pattern    string
+	Handler    HandlerFunc
+	Middleware []Middleware
+	Public     bool        // public routes skip authentication
+	Role       models.Role // role the user must hold, enforced before the handler runs
+	segments   []string
+}
+
+// Use appends route-specific middlewares, run after the router's own chain
+func (r *Route) Use(middlewares ...Middleware) *Route {
+	r.Middleware = append(r.Middleware, middlewares...)
+	return r
+}
+
+// AllowAnonymous marks the route as reachable without an authenticated user
+func (r *Route) AllowAnonymous() *Route {
+	r.Public = true
+	return r
+}
+
+// Table is the route registry consulted by routers.Router.
+// Entity packages declare their own routes on it.
+type Table struct {
+	routes []*Route
+}
+
+func NewTable() *Table {
+	return &Table{}
+}
+
+// Handle registers a handler for the given method and pattern.
+// Pattern segments wrapped in braces ("{id}") capture a path parameter.
+func (t *Table) Handle(method, pattern string, handler HandlerFunc) *Route {
+	r := &Route{
+		Method:   strings.ToUpper(method),
+		Pattern:  pattern,
+		Handler:  handler,
+		segments: splitPath(pattern),
+	}
+	t.routes = append(t.routes, r)
+	return r
+}
+
+// Require declares the role a user must hold to reach the route
+func (r *Route) Require(role models.Role) *Route {
+	r.Role = role
+	return r
+}
+
+// Routes returns every registered route, in registration order
+func (t *Table) Routes() []*Route {
+	return t.routes
+}
+
+// Match is the result of resolving a request against the table
+type Match struct {
+	Route  *Route
+	Params map[string]string
+	Status int      // http.StatusOK, http.StatusNotFound or http.StatusMethodNotAllowed
+	Allow  []string // methods accepted by the path when Status is 405
+}
+
+// Match finds the route for method and path.
+// When several patterns match, the one with more literal segments wins,
+// so "/product/search" takes precedence over "/product/{id}".
+func (t *Table) Match(method, path string) Match {
+	segments := splitPath(path)
+	method = strings.ToUpper(method)
+
+	var best *Route
+	var bestParams map[string]string
+	bestScore := -1
+	allowed := map[string]bool{}
+
+	for _, r := range t.routes {
+		params, score, ok := r.match(segments)
+		if !ok {
+			continue
+		}
+		if r.Method != method {
+			allowed[r.Method] = true
+			continue
+		}
+		if score > bestScore {
+			best, bestParams, bestScore = r, params, score
+		}
+	}
+
+	if best != nil {
+		return Match{Route: best, Params: bestParams, Status: http.StatusOK}
+	}
+
+	if len(allowed) > 0 {
+		allow := make([]string, 0, len(allowed))
+		for m := range allowed {
+			allow = append(allow, m)
+		}
+		sort.Strings(allow)
+		return Match{Status: http.StatusMethodNotAllowed, Allow: allow}
+	}
+
+	return Match{Status: http.StatusNotFound}
+}
+
+// match compares the route pattern with the request segments and returns
+// the captured parameters plus the number of literal segments matched
+func (r *Route) match(segments []string) (map[string]string, int, bool) {
+	if len(segments) != len(r.segments) {
+		return nil, 0, false
+	}
+
+	params := map[string]string{}
+	score := 0
+	for i, pattern := range r.segments {
+		if name, ok := paramName(pattern); ok {
+			if segments[i] == "" {
+				return nil, 0, false
+			}
+			params[name] = segments[i]
+			continue
+		}
+		if pattern != segments[i] {
+			return nil, 0, false
+		}
+		score++
+	}
+
+	return params, score, true
+}
+
+func paramName(segment string) (string, bool) {
+	if len(segment) > 2 && strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+		return segment[1 : len(segment)-1], true
+	}
+	return "", false
+}
+
+// splitPath turns "/admin/users/1" into ["admin", "users", "1"]
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return []string{}
+	}
+	return strings.Split(path, "/")
+}
@@ -0,0 +1,60 @@
+package route
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func noop(requestWithContext models.RequestWithContext) *events.APIGatewayProxyResponse {
+	return nil
+}
+
+// Test route resolution, path parameters and 404/405 handling
+func TestTableMatch(t *testing.T) {
+	table := NewTable()
+	table.Handle(GET, "/product", noop)
+	table.Handle(PUT, "/product/{id}", noop)
+	table.Handle(GET, "/product/search", noop)
+	table.Handle(PUT, "/stock/{productId}", noop)
+	table.Handle(GET, "/admin/users", noop)
+	table.Handle(DELETE, "/admin/users/{id}", noop)
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		status  int
+		pattern string
+		params  map[string]string
+	}{
+		{name: "Static route", method: GET, path: "/product", status: http.StatusOK, pattern: "/product", params: map[string]string{}},
+		{name: "Trailing slash", method: GET, path: "/product/", status: http.StatusOK, pattern: "/product", params: map[string]string{}},
+		{name: "Id parameter", method: PUT, path: "/product/7", status: http.StatusOK, pattern: "/product/{id}", params: map[string]string{"id": "7"}},
+		{name: "Literal wins over parameter", method: GET, path: "/product/search", status: http.StatusOK, pattern: "/product/search", params: map[string]string{}},
+		{name: "Named parameter", method: PUT, path: "/stock/3", status: http.StatusOK, pattern: "/stock/{productId}", params: map[string]string{"productId": "3"}},
+		{name: "Nested parameter", method: DELETE, path: "/admin/users/abc", status: http.StatusOK, pattern: "/admin/users/{id}", params: map[string]string{"id": "abc"}},
+		{name: "Admin alone", method: GET, path: "/admin", status: http.StatusNotFound},
+		{name: "Empty path", method: GET, path: "", status: http.StatusNotFound},
+		{name: "Unknown entity", method: GET, path: "/unknown", status: http.StatusNotFound},
+		{name: "Wrong method", method: POST, path: "/product/7", status: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match := table.Match(tt.method, tt.path)
+			assert.Equal(t, tt.status, match.Status)
+			if tt.status != http.StatusOK {
+				assert.Nil(t, match.Route)
+				return
+			}
+			assert.Equal(t, tt.pattern, match.Route.Pattern)
+			assert.Equal(t, tt.params, match.Params)
+		})
+	}
+
+	assert.Equal(t, []string{PUT}, table.Match(POST, "/product/7").Allow)
+}
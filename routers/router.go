@@ -3,14 +3,15 @@ package routers
 import (
 	"context"
 	"database/sql"
-	"fmt"
 	"net/http"
 	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/ddessilvestri/ecommerce-go/auth"
-	authContext "github.com/ddessilvestri/ecommerce-go/auth/context"
+	"github.com/ddessilvestri/ecommerce-go/db"
 	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/ddessilvestri/ecommerce-go/routers/middleware"
+	"github.com/ddessilvestri/ecommerce-go/routers/route"
 
 	"github.com/ddessilvestri/ecommerce-go/internal/address"
 	adminusers "github.com/ddessilvestri/ecommerce-go/internal/admin/users"
@@ -24,82 +25,79 @@ import (
 
 // HTTP method constants
 const (
-	GET    = "GET"
-	POST   = "POST"
-	PUT    = "PUT"
-	DELETE = "DELETE"
+	GET    = route.GET
+	POST   = route.POST
+	PUT    = route.PUT
+	PATCH  = route.PATCH
+	DELETE = route.DELETE
 )
 
-// Router determines which entity router should handle the request.
-func Router(request events.APIGatewayV2HTTPRequest, urlPrefix string, db *sql.DB) *events.APIGatewayProxyResponse {
-	path := strings.Replace(request.RawPath, urlPrefix, "", 1)
+// Router resolves the request against the route table and dispatches it to the entity handler.
+// The table is built once per pool with NewRouteTable; sqlDB is used to resolve roles.
+func Router(request events.APIGatewayV2HTTPRequest, urlPrefix string, table *route.Table, sqlDB *sql.DB) *events.APIGatewayProxyResponse {
+	path := strings.TrimPrefix(request.RawPath, urlPrefix)
 	method := request.RequestContext.HTTP.Method
-	header := request.Headers
 
-	segments := getPathSegments(path)
+	requestID := request.RequestContext.RequestID
 
-	entityRouter, err := CreateRouter(segments, db)
-	if err != nil {
-		return tools.CreateAPIResponse(http.StatusBadRequest, "Unable to route request: "+err.Error())
+	match := table.Match(method, path)
+	switch match.Status {
+	case http.StatusNotFound:
+		return tools.CreateErrorResponse(requestID, models.NewNotFoundError("unable to route request: path '"+path+"' not found"))
+	case http.StatusMethodNotAllowed:
+		response := tools.CreateErrorResponse(requestID, models.NewMethodNotAllowedError("method not allowed"))
+		response.Headers["Allow"] = strings.Join(match.Allow, ", ")
+		return response
 	}
 
-	var authUser *models.AuthUser
+	request.PathParameters = mergePathParameters(request.PathParameters, match.Params)
+	requestWithContext := models.NewRequestWithContext(request, context.Background())
 
-	if !(segments[0] == "product" && method == "GET") && !(segments[0] == "category" && method == "GET") {
-		authUser, err = auth.ExtractAuthUser(header)
-		if err != nil {
-			return tools.CreateAPIResponse(http.StatusUnauthorized, "Unable to authenticate user: "+err.Error())
-		}
+	resolveRole := func(userUUID string) (models.Role, error) {
+		return db.UserRole(sqlDB, userUUID)
 	}
 
-	context := authContext.WithUser(context.Background(), authUser)
-	requestWithContext := models.NewRequestWithContext(request, context)
-
-	switch method {
-	case GET:
-		return entityRouter.Get(requestWithContext)
-	case POST:
-		return entityRouter.Post(requestWithContext)
-	case PUT:
-		return entityRouter.Put(requestWithContext)
-	case DELETE:
-		return entityRouter.Delete(requestWithContext)
-	default:
-		return tools.CreateAPIResponse(http.StatusMethodNotAllowed, "Method not allowed")
-	}
+	return Dispatch(match.Route, auth.ExtractAuthUser, resolveRole)(requestWithContext)
 }
 
-// CreateRouter maps entity names to their router implementations
-func CreateRouter(segments []string, db *sql.DB) (EntityRouter, error) {
-	switch segments[0] {
-	case "category":
-		return category.NewRouter(db), nil
-	case "product":
-		return product.NewRouter(db), nil
-	case "stock":
-		return stock.NewRouter(db), nil
-	case "address":
-		return address.NewRouter(db), nil
-	case "order":
-		return order.NewRouter(db), nil
-	case "admin":
-		if segments[1] == "users" {
-			return adminusers.NewRouter(db), nil
-		}
-		return nil, fmt.Errorf("path '%s'/'%s' not implemented", segments[0], segments[1])
-	case "user":
-		return user.NewRouter(db), nil // Change API response from HTTP to With Context
-	default:
-		return nil, fmt.Errorf("entity '%s' not implemented", segments[0])
+// Dispatch builds the middleware chain for a route: recovery and timing always run,
+// authentication runs unless the route allows anonymous access, the required role is
+// enforced when the route declares one, then the route's own middlewares.
+func Dispatch(r *route.Route, authenticate middleware.AuthFunc, resolveRole middleware.RoleResolver) route.HandlerFunc {
+	chain := []route.Middleware{middleware.Recover(), middleware.Timing()}
+	if !r.Public {
+		chain = append(chain, middleware.Authenticate(authenticate))
+	}
+	if r.Role != "" {
+		chain = append(chain, middleware.Authorize(resolveRole, r.Role))
 	}
+	chain = append(chain, r.Middleware...)
+
+	return route.Chain(r.Handler, chain...)
 }
 
-// Extract first part of path: "/category/1" -> "category"
+// NewRouteTable collects the routes declared by every entity package
+func NewRouteTable(db *sql.DB) *route.Table {
+	table := route.NewTable()
+	category.RegisterRoutes(table, db)
+	product.RegisterRoutes(table, db)
+	stock.RegisterRoutes(table, db)
+	address.RegisterRoutes(table, db)
+	order.RegisterRoutes(table, db)
+	adminusers.RegisterRoutes(table, db)
+	user.RegisterRoutes(table, db)
+	return table
+}
 
-func getPathSegments(path string) []string {
-	path = strings.Trim(path, "/")
-	if path == "" {
-		return []string{}
+// mergePathParameters overlays the parameters extracted by the route table
+// on top of whatever API Gateway already provided
+func mergePathParameters(gateway, extracted map[string]string) map[string]string {
+	params := make(map[string]string, len(gateway)+len(extracted))
+	for k, v := range gateway {
+		params[k] = v
+	}
+	for k, v := range extracted {
+		params[k] = v
 	}
-	return strings.Split(path, "/")
+	return params
 }
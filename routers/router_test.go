@@ -0,0 +1,111 @@
+package routers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func authenticateAs(uuid string) func(map[string]string) (*models.AuthUser, error) {
+	return func(header map[string]string) (*models.AuthUser, error) {
+		return &models.AuthUser{UUID: uuid}, nil
+	}
+}
+
+func rolesByUUID(userUUID string) (models.Role, error) {
+	if userUUID == "admin-1" {
+		return models.RoleAdmin, nil
+	}
+	return models.RoleCustomer, nil
+}
+
+// dispatch runs a request through the route table without a database.
+// Bodies and ids are invalid on purpose so handlers fail before reaching the DB.
+func dispatch(method, path, userUUID string) *events.APIGatewayProxyResponse {
+	match := NewRouteTable(nil).Match(method, path)
+	if match.Status != http.StatusOK {
+		return &events.APIGatewayProxyResponse{StatusCode: match.Status}
+	}
+
+	request := events.APIGatewayV2HTTPRequest{
+		RawPath:               path,
+		Body:                  "{",
+		PathParameters:        match.Params,
+		QueryStringParameters: map[string]string{"page": "0"},
+	}
+	request.RequestContext.HTTP.Method = method
+	requestWithContext := models.NewRequestWithContext(request, context.Background())
+
+	return Dispatch(match.Route, authenticateAs(userUUID), rolesByUUID)(requestWithContext)
+}
+
+var adminOnlyEndpoints = []struct {
+	method string
+	path   string
+}{
+	{POST, "/product"},
+	{PUT, "/product/x"},
+	{PATCH, "/product/x"},
+	{DELETE, "/product/x"},
+	{POST, "/product/x/restore"},
+	{GET, "/admin/products"},
+	{DELETE, "/admin/search-queries"},
+	{POST, "/category"},
+	{PUT, "/category/x"},
+	{PATCH, "/category/x"},
+	{DELETE, "/category/x"},
+	{POST, "/category/x/move"},
+	{PUT, "/stock/x"},
+	{GET, "/admin/users"},
+	{DELETE, "/admin/users/x"},
+	{POST, "/order/x/status"},
+	{GET, "/admin/orders/x/timeline"},
+}
+
+// Test customers are rejected from every catalog, stock and admin write endpoint
+func TestCustomerCannotReachAdminEndpoints(t *testing.T) {
+	for _, e := range adminOnlyEndpoints {
+		t.Run(e.method+" "+e.path, func(t *testing.T) {
+			response := dispatch(e.method, e.path, "customer-1")
+			assert.Equal(t, http.StatusForbidden, response.StatusCode)
+		})
+	}
+}
+
+// Test admins get past authorization and reach the handler
+func TestAdminReachesAdminEndpoints(t *testing.T) {
+	for _, e := range adminOnlyEndpoints {
+		t.Run(e.method+" "+e.path, func(t *testing.T) {
+			response := dispatch(e.method, e.path, "admin-1")
+			assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+		})
+	}
+}
+
+// Test every non-GET catalog route declares the admin role, so new routes can't slip through
+func TestCatalogWritesRequireAdmin(t *testing.T) {
+	for _, r := range NewRouteTable(nil).Routes() {
+		entity := strings.Split(strings.Trim(r.Pattern, "/"), "/")[0]
+		isCatalog := entity == "product" || entity == "category" || entity == "stock"
+		if isCatalog && r.Method != GET {
+			assert.Equal(t, models.RoleAdmin, r.Role, r.Method+" "+r.Pattern)
+		}
+	}
+}
+
+// Test role hierarchy used by the router
+func TestRoleSatisfies(t *testing.T) {
+	assert.True(t, models.RoleAdmin.Satisfies(models.RoleAdmin))
+	assert.True(t, models.RoleAdmin.Satisfies(models.RoleCustomer))
+	assert.True(t, models.RoleCustomer.Satisfies(models.RoleCustomer))
+	assert.True(t, models.RoleCustomer.Satisfies(""))
+	assert.False(t, models.RoleCustomer.Satisfies(models.RoleAdmin))
+	assert.False(t, models.Role("").Satisfies(models.RoleAdmin))
+	assert.Equal(t, models.RoleAdmin, models.RoleFromStatus(0))
+	assert.Equal(t, models.RoleCustomer, models.RoleFromStatus(1))
+}
@@ -0,0 +1,60 @@
+package tools
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/ddessilvestri/ecommerce-go/models"
+)
+
+var ErrInvalidCursor = models.NewFieldError("cursor", "invalid 'cursor' parameter")
+var ErrCursorSortMismatch = models.NewFieldError("cursor", "'cursor' was issued for another sort_by/order")
+
+// EncodeCursor turns a cursor into the opaque string handed to clients
+func EncodeCursor(cursor models.Cursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// ParseCursor reads ?cursor=, returning nil when absent.
+// The cursor must have been issued for the same sort_by and order as the request.
+func ParseCursor(query map[string]string, sortBy, order string) (*models.Cursor, error) {
+	val := strings.TrimSpace(query["cursor"])
+	if val == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(val)
+	if err != nil {
+		return nil, ErrInvalidCursor.Wrap(err)
+	}
+	var cursor models.Cursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, ErrInvalidCursor.Wrap(err)
+	}
+	if cursor.Id < 1 || cursor.Value == nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.SortBy != sortBy || cursor.Order != order {
+		return nil, ErrCursorSortMismatch
+	}
+	return &cursor, nil
+}
+
+// KeysetAfter matches the rows that come after cursor when ordering by column then idColumn,
+// both in the cursor's direction
+func KeysetAfter(column, idColumn string, cursor *models.Cursor) squirrel.Sqlizer {
+	op := " > ?"
+	if cursor.Order == "DESC" {
+		op = " < ?"
+	}
+	return squirrel.Or{
+		squirrel.Expr(column+op, cursor.Value),
+		squirrel.And{
+			squirrel.Expr(column+" = ?", cursor.Value),
+			squirrel.Expr(idColumn+op, cursor.Id),
+		},
+	}
+}
@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test a cursor survives the round trip and is tied to its sort
+func TestParseCursor(t *testing.T) {
+	encoded := EncodeCursor(models.Cursor{SortBy: "price", Order: "DESC", Value: 19.5, Id: 42})
+
+	cursor, err := ParseCursor(map[string]string{"cursor": encoded}, "price", "DESC")
+	require.NoError(t, err)
+	assert.Equal(t, 19.5, cursor.Value)
+	assert.Equal(t, 42, cursor.Id)
+
+	_, err = ParseCursor(map[string]string{"cursor": encoded}, "title", "DESC")
+	assert.ErrorIs(t, err, ErrCursorSortMismatch)
+
+	_, err = ParseCursor(map[string]string{"cursor": "not-a-cursor"}, "price", "DESC")
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	cursor, err = ParseCursor(map[string]string{}, "price", "DESC")
+	assert.NoError(t, err)
+	assert.Nil(t, cursor)
+}
+
+// Test the keyset predicate breaks ties on the id in the same direction
+func TestKeysetAfter(t *testing.T) {
+	query, args, err := KeysetAfter("Prod_Price", "Prod_Id", &models.Cursor{Order: "ASC", Value: 10.0, Id: 7}).ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "(Prod_Price > ? OR (Prod_Price = ? AND Prod_Id > ?))", query)
+	assert.Equal(t, []interface{}{10.0, 10.0, 7}, args)
+
+	query, _, err = KeysetAfter("Prod_Price", "Prod_Id", &models.Cursor{Order: "DESC", Value: 10.0, Id: 7}).ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "(Prod_Price < ? OR (Prod_Price = ? AND Prod_Id < ?))", query)
+}
+
+// Test cursor pages link forward with the cursor instead of a page number
+func TestPageResponseCursor(t *testing.T) {
+	request := events.APIGatewayV2HTTPRequest{
+		RawPath:               "/product",
+		QueryStringParameters: map[string]string{"cursor": "abc", "limit": "2"},
+	}
+	requestWithContext := models.NewRequestWithContext(request, context.Background())
+
+	response := PageResponse(requestWithContext, models.NewCursorPage([]int{1, 2}, 2, 5, "def"))
+
+	var body models.Page[int]
+	require.NoError(t, json.Unmarshal([]byte(response.Body), &body))
+	assert.True(t, body.HasNext)
+	assert.Equal(t, "def", body.NextCursor)
+	assert.Equal(t, "/product?cursor=def&limit=2", body.Next)
+	assert.Empty(t, body.Prev)
+}
@@ -0,0 +1,84 @@
+package tools
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ddessilvestri/ecommerce-go/models"
+)
+
+// ErrorBody is the JSON envelope returned for every failed request
+type ErrorBody struct {
+	Error ErrorPayload `json:"error"`
+}
+
+type ErrorPayload struct {
+	Code      string              `json:"code"`
+	Message   string              `json:"message"`
+	Details   []models.FieldError `json:"details,omitempty"`
+	RequestID string              `json:"requestId,omitempty"`
+}
+
+var statusByKind = map[models.ErrorKind]int{
+	models.ErrKindValidation:       http.StatusBadRequest,
+	models.ErrKindNotFound:         http.StatusNotFound,
+	models.ErrKindConflict:         http.StatusConflict,
+	models.ErrKindForbidden:        http.StatusForbidden,
+	models.ErrKindUnauthorized:     http.StatusUnauthorized,
+	models.ErrKindMethodNotAllowed: http.StatusMethodNotAllowed,
+}
+
+// MapError turns any error into an HTTP status and envelope payload.
+// Typed domain errors keep their own message, while the cause they wrap is only logged;
+// sql.ErrNoRows is a 404; anything else is logged and reported as a generic 500.
+func MapError(err error) (int, ErrorPayload) {
+	var domainErr *models.DomainError
+	if errors.As(err, &domainErr) {
+		status, ok := statusByKind[domainErr.Kind]
+		if !ok {
+			status = http.StatusInternalServerError
+		}
+		if domainErr.Err != nil {
+			fmt.Println("Domain error cause:", err.Error())
+		}
+		return status, ErrorPayload{
+			Code:    string(domainErr.Kind),
+			Message: domainErr.Message,
+			Details: domainErr.Fields,
+		}
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return http.StatusNotFound, ErrorPayload{
+			Code:    string(models.ErrKindNotFound),
+			Message: "resource not found",
+		}
+	}
+
+	fmt.Println("Internal error:", err.Error())
+	return http.StatusInternalServerError, ErrorPayload{
+		Code:    "internal_error",
+		Message: "internal server error",
+	}
+}
+
+// CreateErrorResponse builds the JSON error envelope for err
+func CreateErrorResponse(requestID string, err error) *events.APIGatewayProxyResponse {
+	status, payload := MapError(err)
+	payload.RequestID = requestID
+
+	body, marshalErr := json.Marshal(ErrorBody{Error: payload})
+	if marshalErr != nil {
+		return CreateAPIResponse(http.StatusInternalServerError, `{"error":{"code":"internal_error","message":"internal server error"}}`)
+	}
+	return CreateAPIResponse(status, string(body))
+}
+
+// ErrorResponse is CreateErrorResponse using the request id of the incoming request
+func ErrorResponse(requestWithContext models.RequestWithContext, err error) *events.APIGatewayProxyResponse {
+	return CreateErrorResponse(requestWithContext.RequestID(), err)
+}
@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test typed errors map to their HTTP status and code
+func TestMapError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{name: "Validation", err: models.NewFieldError("id", "invalid Id"), status: http.StatusBadRequest, code: "validation_error"},
+		{name: "Not found", err: models.NewNotFoundError("address not found"), status: http.StatusNotFound, code: "not_found"},
+		{name: "Conflict", err: models.NewConflictError("category in use"), status: http.StatusConflict, code: "conflict"},
+		{name: "Forbidden", err: models.NewForbiddenError("access denied"), status: http.StatusForbidden, code: "forbidden"},
+		{name: "Unauthorized", err: models.NewUnauthorizedError("missing token"), status: http.StatusUnauthorized, code: "unauthorized"},
+		{name: "Wrapped domain error", err: fmt.Errorf("service: %w", models.NewNotFoundError("order not found")), status: http.StatusNotFound, code: "not_found"},
+		{name: "No rows", err: sql.ErrNoRows, status: http.StatusNotFound, code: "not_found"},
+		{name: "Unknown", err: errors.New("connection refused"), status: http.StatusInternalServerError, code: "internal_error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, payload := MapError(tt.err)
+			assert.Equal(t, tt.status, status)
+			assert.Equal(t, tt.code, payload.Code)
+		})
+	}
+}
+
+// Test the envelope is valid JSON and carries details and request id
+func TestCreateErrorResponse(t *testing.T) {
+	err := models.NewFieldError("prodTitle", "invalid product: title is required")
+	response := CreateErrorResponse("req-1", err)
+
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	assert.Equal(t, "application/json", response.Headers["Content-Type"])
+
+	var body ErrorBody
+	require.NoError(t, json.Unmarshal([]byte(response.Body), &body))
+	assert.Equal(t, "validation_error", body.Error.Code)
+	assert.Equal(t, "invalid product: title is required", body.Error.Message)
+	assert.Equal(t, "req-1", body.Error.RequestID)
+	assert.Equal(t, []models.FieldError{{Field: "prodTitle", Message: "invalid product: title is required"}}, body.Error.Details)
+
+	// Internal errors never leak their message
+	response = CreateErrorResponse("req-2", errors.New("dial tcp 10.0.0.1:3306: timeout"))
+	assert.NotContains(t, response.Body, "10.0.0.1")
+}
+
+// Test a domain error sends only its own message, not the cause it wraps
+func TestCreateErrorResponseHidesCause(t *testing.T) {
+	err := models.NewFieldError("id", "invalid Id").Wrap(errors.New("strconv.Atoi: parsing \"x\": invalid syntax"))
+	response := CreateErrorResponse("req-3", fmt.Errorf("handler: %w", err))
+
+	var body ErrorBody
+	require.NoError(t, json.Unmarshal([]byte(response.Body), &body))
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	assert.Equal(t, "invalid Id", body.Error.Message)
+	assert.NotContains(t, response.Body, "strconv")
+}
+
+// Test wrapped sentinels still match with errors.Is
+func TestDomainErrorIs(t *testing.T) {
+	sentinel := models.NewFieldError("id", "invalid Id")
+	wrapped := sentinel.Wrap(errors.New("strconv.Atoi: parsing \"x\": invalid syntax"))
+
+	assert.ErrorIs(t, wrapped, sentinel)
+	assert.Contains(t, wrapped.Error(), "invalid syntax")
+}
@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ddessilvestri/ecommerce-go/models"
+)
+
+// PageResponse fills the next/prev links of page from the current request and writes it as a 200.
+// Cursor pages only link forward.
+func PageResponse[T any](requestWithContext models.RequestWithContext, page models.Page[T]) *events.APIGatewayProxyResponse {
+	path := requestWithContext.RequestPath()
+	query := requestWithContext.RequestQueryStringParameters()
+
+	switch {
+	case page.Page == 0 && page.NextCursor != "":
+		page.Next = CursorLink(path, query, page.NextCursor)
+	case page.HasNext:
+		page.Next = PageLink(path, query, page.Page+1)
+	}
+	if page.Page > 1 {
+		page.Prev = PageLink(path, query, page.Page-1)
+	}
+
+	body, err := json.Marshal(page)
+	if err != nil {
+		return ErrorResponse(requestWithContext, err)
+	}
+	return CreateAPIResponse(http.StatusOK, string(body))
+}
+
+// PageLink rebuilds path with the same query string pointing at another page
+func PageLink(path string, query map[string]string, page int) string {
+	values := url.Values{}
+	for key, value := range query {
+		values.Set(key, value)
+	}
+	values.Set("page", strconv.Itoa(page))
+	return path + "?" + values.Encode()
+}
+
+// CursorLink rebuilds path with the same query string continuing after cursor
+func CursorLink(path string, query map[string]string, cursor string) string {
+	values := url.Values{}
+	for key, value := range query {
+		values.Set(key, value)
+	}
+	values.Del("page")
+	values.Set("cursor", cursor)
+	return path + "?" + values.Encode()
+}
+
+// ListResponse writes page keeping only the fields selected by q (?fields=)
+func ListResponse[T any](requestWithContext models.RequestWithContext, page models.Page[T], q Query) *events.APIGatewayProxyResponse {
+	if len(q.Fields) == 0 {
+		return PageResponse(requestWithContext, page)
+	}
+	projected, err := SelectFields(page, q.Fields)
+	if err != nil {
+		return ErrorResponse(requestWithContext, err)
+	}
+	return PageResponse(requestWithContext, projected)
+}
+
+// SelectFields projects every item of page onto the given JSON keys
+func SelectFields[T any](page models.Page[T], keys []string) (models.Page[map[string]json.RawMessage], error) {
+	items := make([]map[string]json.RawMessage, 0, len(page.Items))
+	for _, item := range page.Items {
+		raw, err := json.Marshal(item)
+		if err != nil {
+			return models.Page[map[string]json.RawMessage]{}, err
+		}
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &all); err != nil {
+			return models.Page[map[string]json.RawMessage]{}, err
+		}
+
+		selected := make(map[string]json.RawMessage, len(keys))
+		for _, key := range keys {
+			if value, ok := all[key]; ok {
+				selected[key] = value
+			}
+		}
+		items = append(items, selected)
+	}
+
+	return models.Page[map[string]json.RawMessage]{
+		Items:      items,
+		Page:       page.Page,
+		Limit:      page.Limit,
+		Total:      page.Total,
+		HasNext:    page.HasNext,
+		NextCursor: page.NextCursor,
+	}, nil
+}
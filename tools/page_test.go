@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test the envelope reports totals and links to the neighbouring pages
+func TestPageResponse(t *testing.T) {
+	request := events.APIGatewayV2HTTPRequest{
+		RawPath:               "/gambit/product",
+		QueryStringParameters: map[string]string{"page": "2", "limit": "2", "sort_by": "price"},
+	}
+	requestWithContext := models.NewRequestWithContext(request, context.Background())
+
+	page := models.NewPage([]string{"c", "d"}, 2, 2, 5)
+	response := PageResponse(requestWithContext, page)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+
+	var body models.Page[string]
+	require.NoError(t, json.Unmarshal([]byte(response.Body), &body))
+	assert.Equal(t, []string{"c", "d"}, body.Items)
+	assert.Equal(t, 5, body.Total)
+	assert.True(t, body.HasNext)
+	assert.Equal(t, "/gambit/product?limit=2&page=3&sort_by=price", body.Next)
+	assert.Equal(t, "/gambit/product?limit=2&page=1&sort_by=price", body.Prev)
+}
+
+// Test the last page has no next link and empty pages serialize as []
+func TestNewPageBounds(t *testing.T) {
+	last := models.NewPage([]string{"e"}, 3, 2, 5)
+	assert.False(t, last.HasNext)
+
+	empty := models.NewPage[string](nil, 1, 10, 0)
+	assert.False(t, empty.HasNext)
+	raw, err := json.Marshal(empty)
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), `"items":[]`)
+}
+
+// Test ?fields= keeps only the selected JSON keys
+func TestSelectFields(t *testing.T) {
+	page := models.NewPage([]models.Address{{Id: 1, Title: "Home", City: "Madrid"}}, 1, 10, 1)
+
+	projected, err := SelectFields(page, []string{"id", "city"})
+	require.NoError(t, err)
+	require.Len(t, projected.Items, 1)
+	assert.Equal(t, map[string]json.RawMessage{"id": json.RawMessage("1"), "city": json.RawMessage(`"Madrid"`)}, projected.Items[0])
+	assert.Equal(t, 1, projected.Total)
+}
@@ -0,0 +1,27 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/ddessilvestri/ecommerce-go/models"
+)
+
+// ParseMergePatch decodes a JSON Merge Patch document into patch (a *models.XxxPatch).
+// The body must be a non-empty JSON object and may only name known fields.
+func ParseMergePatch(body string, patch interface{}) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(body), &fields); err != nil {
+		return models.NewValidationError("invalid JSON merge patch: body must be a JSON object").Wrap(err)
+	}
+	if len(fields) == 0 {
+		return models.NewValidationError("invalid JSON merge patch: no fields to update")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(body)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(patch); err != nil {
+		return models.NewValidationError("invalid JSON merge patch").Wrap(err)
+	}
+	return nil
+}
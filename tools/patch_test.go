@@ -0,0 +1,40 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test absent, null and zero values are told apart
+func TestParseMergePatch(t *testing.T) {
+	var patch models.ProductPatch
+	err := ParseMergePatch(`{"prodDescription": null, "prodStock": 0, "prodPrice": 0}`, &patch)
+	require.NoError(t, err)
+
+	assert.False(t, patch.Title.Set)
+	assert.True(t, patch.Description.Set)
+	assert.True(t, patch.Description.Null)
+	assert.Nil(t, patch.Description.SQLValue())
+	assert.True(t, patch.Stock.Set)
+	assert.False(t, patch.Stock.Null)
+	assert.Equal(t, 0, patch.Stock.SQLValue())
+	assert.True(t, patch.Price.Set)
+	assert.Equal(t, 0.0, patch.Price.Value)
+}
+
+// Test invalid documents are validation errors
+func TestParseMergePatchInvalid(t *testing.T) {
+	bodies := []string{`[]`, `{}`, `{"unknown": 1}`, `{"prodStock": "ten"}`, `not json`}
+
+	for _, body := range bodies {
+		t.Run(body, func(t *testing.T) {
+			var patch models.ProductPatch
+			err := ParseMergePatch(body, &patch)
+			status, _ := MapError(err)
+			assert.Equal(t, 400, status)
+		})
+	}
+}
@@ -0,0 +1,278 @@
+package tools
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/ddessilvestri/ecommerce-go/models"
+)
+
+// FieldType tells the parser how to validate filter values
+type FieldType int
+
+const (
+	TextField FieldType = iota
+	IntField
+	NumberField
+	DateField
+)
+
+// Field declares one API field of a list endpoint
+type Field struct {
+	Column     string // SQL expression the field maps to
+	JSON       string // key in the JSON response, used by ?fields=
+	Type       FieldType
+	Sortable   bool
+	Filterable bool
+}
+
+// QuerySpec declares once what a list endpoint can sort, filter and select on
+type QuerySpec struct {
+	Fields       map[string]Field
+	IdColumn     string // unique column appended to every ORDER BY so pages are stable
+	DefaultSort  string
+	DefaultOrder string
+	Cursor       bool // keyset pagination (?cursor=) is supported; IdColumn must be an int
+}
+
+// Query is a list request validated against a QuerySpec
+type Query struct {
+	Page    int
+	Limit   int
+	SortBy  string
+	Order   string
+	After   *models.Cursor // set in cursor mode
+	Filters squirrel.And
+	Fields  []string // JSON keys to keep in the response, empty keeps every field
+
+	spec *QuerySpec
+}
+
+// filterOperators are the suffixes accepted after a filterable field name, e.g. price_gte
+var filterOperators = []struct {
+	suffix string
+	op     string
+}{
+	{"_gte", ">="},
+	{"_lte", "<="},
+	{"_gt", ">"},
+	{"_lt", "<"},
+	{"_ne", "<>"},
+}
+
+// reservedParameters are never read as filters
+var reservedParameters = map[string]bool{
+	"page": true, "limit": true, "sort_by": true, "order": true, "cursor": true, "fields": true,
+}
+
+// Parse validates pagination, sorting, filters and field selection.
+// Query string keys that are neither reserved nor filters are left to the handler (e.g. ?search=).
+func (s *QuerySpec) Parse(query map[string]string) (Query, error) {
+	q := Query{SortBy: s.DefaultSort, Order: s.DefaultOrder, spec: s}
+
+	var err error
+	q.Page, q.Limit, err = ParsePageAndLimit(query)
+	if err != nil {
+		return Query{}, err
+	}
+
+	if val := strings.TrimSpace(query["sort_by"]); val != "" {
+		if !s.Fields[val].Sortable {
+			return Query{}, models.NewFieldError("sort_by", "invalid 'sort_by' parameter")
+		}
+		q.SortBy = val
+	}
+
+	if val := strings.ToUpper(strings.TrimSpace(query["order"])); val != "" {
+		if val != "ASC" && val != "DESC" {
+			return Query{}, models.NewFieldError("order", "invalid 'order' parameter")
+		}
+		q.Order = val
+	}
+
+	q.After, err = ParseCursor(query, q.SortBy, q.Order)
+	if err != nil {
+		return Query{}, err
+	}
+	if q.After != nil && !s.Cursor {
+		return Query{}, models.NewFieldError("cursor", "'cursor' is not supported by this endpoint")
+	}
+
+	if val := strings.TrimSpace(query["fields"]); val != "" {
+		for _, name := range strings.Split(val, ",") {
+			field, ok := s.Fields[strings.TrimSpace(name)]
+			if !ok || field.JSON == "" {
+				return Query{}, models.NewFieldError("fields", fmt.Sprintf("unknown field %q in 'fields' parameter", name))
+			}
+			q.Fields = append(q.Fields, field.JSON)
+		}
+	}
+
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if reservedParameters[key] {
+			continue
+		}
+		clause, ok, err := s.filter(key, strings.TrimSpace(query[key]))
+		if err != nil {
+			return Query{}, err
+		}
+		if ok {
+			q.Filters = append(q.Filters, clause)
+		}
+	}
+
+	return q, nil
+}
+
+// filter turns "price_gte=10" into "Prod_Price >= ?"; ok is false when key is not a filter
+func (s *QuerySpec) filter(key, raw string) (clause squirrel.Sqlizer, ok bool, err error) {
+	name, op := key, "="
+	if _, exact := s.Fields[key]; !exact {
+		for _, o := range filterOperators {
+			if strings.HasSuffix(key, o.suffix) {
+				name, op = strings.TrimSuffix(key, o.suffix), o.op
+				break
+			}
+		}
+	}
+
+	field, known := s.Fields[name]
+	if !known || !field.Filterable {
+		return nil, false, nil
+	}
+
+	invalid := models.NewFieldError(key, fmt.Sprintf("invalid '%s' parameter", key))
+	switch field.Type {
+	case IntField:
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, false, invalid.Wrap(err)
+		}
+		return squirrel.Expr(field.Column+" "+op+" ?", value), true, nil
+	case NumberField:
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, false, invalid.Wrap(err)
+		}
+		return squirrel.Expr(field.Column+" "+op+" ?", value), true, nil
+	case DateField:
+		return dateFilter(field.Column, op, raw, invalid)
+	default:
+		if op != "=" && op != "<>" {
+			return nil, false, invalid
+		}
+		return squirrel.Expr(field.Column+" "+op+" ?", raw), true, nil
+	}
+}
+
+// dateFilter compares a DATETIME column with a date or timestamp.
+// A bare date covers its whole day, so created_at_lte=2024-01-31 includes the 31st.
+func dateFilter(column, op, raw string, invalid *models.DomainError) (squirrel.Sqlizer, bool, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return squirrel.Expr(column+" "+op+" ?", t.UTC().Format("2006-01-02 15:04:05")), true, nil
+	}
+
+	day, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return nil, false, invalid.Wrap(err)
+	}
+	start := day.Format("2006-01-02")
+	next := day.AddDate(0, 0, 1).Format("2006-01-02")
+
+	switch op {
+	case "=":
+		return squirrel.Expr(column+" >= ? AND "+column+" < ?", start, next), true, nil
+	case "<>":
+		return squirrel.Expr("("+column+" < ? OR "+column+" >= ?)", start, next), true, nil
+	case ">=", "<":
+		return squirrel.Expr(column+" "+op+" ?", start), true, nil
+	case ">":
+		return squirrel.Expr(column+" >= ?", next), true, nil
+	default: // "<="
+		return squirrel.Expr(column+" < ?", next), true, nil
+	}
+}
+
+// SortColumn is the SQL expression of the requested sort
+func (q Query) SortColumn() string {
+	return q.spec.Fields[q.SortBy].Column
+}
+
+// Where is the filter (and keyset) condition of the query, without paging
+func (q Query) Where() squirrel.And {
+	where := squirrel.And{}
+	where = append(where, q.Filters...)
+	if q.After != nil {
+		where = append(where, KeysetAfter(q.SortColumn(), q.spec.IdColumn, q.After))
+	}
+	return where
+}
+
+// ApplyTo adds the filters, ordering and paging of the query to a SELECT
+func (q Query) ApplyTo(builder squirrel.SelectBuilder) squirrel.SelectBuilder {
+	if where := q.Where(); len(where) > 0 {
+		builder = builder.Where(where)
+	}
+
+	builder = builder.OrderBy(q.SortColumn() + " " + q.Order)
+	if q.SortColumn() != q.spec.IdColumn {
+		builder = builder.OrderBy(q.spec.IdColumn + " " + q.Order)
+	}
+
+	builder = builder.Limit(uint64(q.Limit))
+	if q.After == nil {
+		builder = builder.Offset(uint64((q.Page - 1) * q.Limit))
+	}
+	return builder
+}
+
+// ApplyFiltersTo only adds the filters of the query, e.g. to the matching COUNT(*)
+func (q Query) ApplyFiltersTo(builder squirrel.SelectBuilder) squirrel.SelectBuilder {
+	if len(q.Filters) > 0 {
+		builder = builder.Where(q.Filters)
+	}
+	return builder
+}
+
+// Fetch is the query to run against the repository: cursor pages ask for one extra row
+// so NewListPage can tell whether another page follows
+func (q Query) Fetch() Query {
+	if q.After != nil {
+		q.Limit++
+	}
+	return q
+}
+
+// NewListPage builds the response page of q from rows fetched with q.Fetch().
+// key returns the sort value and id of a row for cursors; it may be nil when the spec has no cursor.
+func NewListPage[T any](items []T, q Query, total int, key func(item T) (interface{}, int)) models.Page[T] {
+	cursorOf := func(item T) string {
+		value, id := key(item)
+		return EncodeCursor(models.Cursor{SortBy: q.SortBy, Order: q.Order, Value: value, Id: id})
+	}
+
+	if q.After != nil {
+		var next string
+		if len(items) > q.Limit {
+			items = items[:q.Limit]
+			next = cursorOf(items[q.Limit-1])
+		}
+		return models.NewCursorPage(items, q.Limit, total, next)
+	}
+
+	page := models.NewPage(items, q.Page, q.Limit, total)
+	if key != nil && page.HasNext && len(items) > 0 {
+		page.NextCursor = cursorOf(items[len(items)-1])
+	}
+	return page
+}
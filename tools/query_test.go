@@ -0,0 +1,79 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/ddessilvestri/ecommerce-go/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSpec = QuerySpec{
+	Fields: map[string]Field{
+		"id":         {Column: "T_Id", JSON: "id", Type: IntField, Sortable: true},
+		"email":      {Column: "T_Email", JSON: "email", Sortable: true, Filterable: true},
+		"price":      {Column: "T_Price", JSON: "price", Type: NumberField, Sortable: true, Filterable: true},
+		"status":     {Column: "T_Status", JSON: "status", Type: IntField, Filterable: true},
+		"created_at": {Column: "T_Created", JSON: "createdAt", Type: DateField, Filterable: true},
+	},
+	IdColumn:     "T_Id",
+	DefaultSort:  "id",
+	DefaultOrder: "ASC",
+}
+
+func toSql(t *testing.T, q Query) (string, []interface{}) {
+	query, args, err := q.ApplyTo(squirrel.Select("*").From("t")).ToSql()
+	require.NoError(t, err)
+	return query, args
+}
+
+// Test defaults produce a plain ordered page
+func TestQuerySpecDefaults(t *testing.T) {
+	q, err := testSpec.Parse(map[string]string{})
+	require.NoError(t, err)
+
+	query, args := toSql(t, q)
+	assert.Equal(t, "SELECT * FROM t ORDER BY T_Id ASC LIMIT 10 OFFSET 0", query)
+	assert.Empty(t, args)
+}
+
+// Test sort, filters and date ranges become validated clauses
+func TestQuerySpecParse(t *testing.T) {
+	q, err := testSpec.Parse(map[string]string{
+		"sort_by":        "email",
+		"order":          "desc",
+		"page":           "3",
+		"limit":          "5",
+		"price_gte":      "10.5",
+		"status":         "1",
+		"created_at_lte": "2024-01-31",
+		"search":         "ignored by the spec",
+		"fields":         "id,email",
+	})
+	require.NoError(t, err)
+
+	query, args := toSql(t, q)
+	assert.Equal(t, "SELECT * FROM t WHERE (T_Created < ? AND T_Price >= ? AND T_Status = ?) ORDER BY T_Email DESC, T_Id DESC LIMIT 5 OFFSET 10", query)
+	assert.Equal(t, []interface{}{"2024-02-01", 10.5, 1}, args)
+	assert.Equal(t, []string{"id", "email"}, q.Fields)
+}
+
+// Test invalid sort keys, filter values and fields are rejected
+func TestQuerySpecInvalid(t *testing.T) {
+	invalid := []map[string]string{
+		{"sort_by": "status"},
+		{"order": "sideways"},
+		{"price_lt": "cheap"},
+		{"created_at": "yesterday"},
+		{"email_gte": "a"},
+		{"fields": "id,password"},
+		{"cursor": EncodeCursor(models.Cursor{SortBy: "id", Order: "ASC", Value: 1, Id: 1})},
+	}
+
+	for _, query := range invalid {
+		_, err := testSpec.Parse(query)
+		status, _ := MapError(err)
+		assert.Equal(t, 400, status, query)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/ddessilvestri/ecommerce-go/models"
 )
 
 func DateMySQL() string {
@@ -32,14 +33,14 @@ func EscapeString(t string) string {
 	return desc
 }
 
-func ParsePaginationAndSorting(query map[string]string) (page, limit int, sortBy, order string, err error) {
+// ParsePageAndLimit reads ?page= and ?limit=, defaulting to the first page of 10
+func ParsePageAndLimit(query map[string]string) (page, limit int, err error) {
 	page, limit = 1, 10
-	sortBy, order = "id", "ASC"
 
 	if val := strings.TrimSpace(query["page"]); val != "" {
 		p, err := strconv.Atoi(val)
 		if err != nil || p < 1 {
-			return 0, 0, "", "", fmt.Errorf("invalid 'page' parameter")
+			return 0, 0, models.NewFieldError("page", "invalid 'page' parameter")
 		}
 		page = p
 	}
@@ -47,53 +48,10 @@ func ParsePaginationAndSorting(query map[string]string) (page, limit int, sortBy
 	if val := strings.TrimSpace(query["limit"]); val != "" {
 		l, err := strconv.Atoi(val)
 		if err != nil || l < 1 {
-			return 0, 0, "", "", fmt.Errorf("invalid 'limit' parameter")
+			return 0, 0, models.NewFieldError("limit", "invalid 'limit' parameter")
 		}
 		limit = l
 	}
 
-	if val := strings.TrimSpace(query["sort_by"]); val != "" {
-		allowed := map[string]bool{
-			"id": true, "title": true, "description": true, "price": true,
-			"category_id": true, "stock": true, "created_at": true,
-		}
-		if !allowed[val] {
-			return 0, 0, "", "", fmt.Errorf("invalid 'sort_by' parameter")
-		}
-		sortBy = val
-	}
-
-	if val := strings.ToUpper(strings.TrimSpace(query["order"])); val != "" {
-		if val != "ASC" && val != "DESC" {
-			return 0, 0, "", "", fmt.Errorf("invalid 'order' parameter")
-		}
-		order = val
-	}
-
-	return
-}
-
-func ParseOrdersPaginationAndSorting(query map[string]string) (page int, from_date, to_date string, err error) {
-	page = 1
-	from_date = "1970-01-01"
-	to_date = time.Now().Format("2006-01-02")
-
-	if val := strings.TrimSpace(query["page"]); val != "" {
-		p, err := strconv.Atoi(val)
-		if err != nil || p < 1 {
-			return 0, from_date, to_date, fmt.Errorf("invalid 'page' parameter")
-		}
-		page = p
-	}
-
-	if val := strings.TrimSpace(query["from_date"]); val != "" {
-
-		from_date = val
-	}
-
-	if val := strings.TrimSpace(query["to_date"]); val != "" {
-		to_date = val
-	}
-
-	return
+	return page, limit, nil
 }